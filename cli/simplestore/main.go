@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	// "errors"go
 	"fmt"
@@ -47,6 +48,7 @@ var txFlags struct {
 	ContractAddr string
 	ChainID      string
 	PrivFile     string
+	TLS          loomclient.TLSOptions
 }
 
 func init() {
@@ -69,11 +71,15 @@ func main() {
 		Short: "call a contract method",
 	}
 	pflags := callCmd.PersistentFlags()
-	pflags.StringVarP(&txFlags.WriteURI, "write", "w", "http://localhost:46658/rpc", "URI for sending txs")
-	pflags.StringVarP(&txFlags.ReadURI, "read", "r", "http://localhost:46658/query", "URI for quering app state")
+	pflags.StringVarP(&txFlags.WriteURI, "write", "w", "http://localhost:46658/rpc", "URI for sending txs (http, https, tcp or unix)")
+	pflags.StringVarP(&txFlags.ReadURI, "read", "r", "http://localhost:46658/query", "URI for quering app state (http, https, tcp or unix)")
 	pflags.StringVarP(&txFlags.ContractAddr, "contract", "", "SimpleStore", "contract address")
 	pflags.StringVarP(&txFlags.ChainID, "chain", "", "default", "chain ID")
 	pflags.StringVarP(&txFlags.PrivFile, "private-key", "p", "", "private key file")
+	pflags.StringVarP(&txFlags.TLS.CAFile, "cacert", "", "", "PEM file of the CAs to verify https nodes with")
+	pflags.StringVarP(&txFlags.TLS.CertFile, "cert", "", "", "PEM file of the client certificate for https nodes")
+	pflags.StringVarP(&txFlags.TLS.KeyFile, "cert-key", "", "", "PEM file of the key of the client certificate")
+	pflags.BoolVarP(&txFlags.TLS.InsecureSkipVerify, "insecure", "", false, "accept any certificate from https nodes")
 
 	rootCmd.AddCommand(callCmd)
 	rootCmd.AddCommand(getKeygenCmd())
@@ -96,7 +102,7 @@ func main() {
 				return err
 			}
 
-			err = c.Call("Set", msg, nil)
+			err = c.Call(context.Background(), "Set", msg, nil)
 			if err != nil {
 				return err
 			}
@@ -168,9 +174,15 @@ func newLoomClient() (*loomclient.ContractClient, error) {
 	}
 	signer := auth.NewEd25519Signer(privKey)
 
-	httpclient := &http.Client{}
+	tlsConfig, err := txFlags.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	httpclient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
 	rpcClient := loomclient.NewDAppChainRPCClient(httpclient, txFlags.ChainID, txFlags.WriteURI, txFlags.ReadURI)
-	client, err := loomclient.NewContractClient(txFlags.ContractAddr, txFlags.ChainID, signer, rpcClient)
+	client, err := loomclient.NewContractClient(context.Background(), txFlags.ContractAddr, txFlags.ChainID, signer, rpcClient)
 
 	return client, err
 }
@@ -0,0 +1,282 @@
+package loomclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/loomnetwork/go-loom"
+	"github.com/loomnetwork/go-loom/auth"
+	"github.com/loomnetwork/go-loom/plugin"
+	ptypes "github.com/loomnetwork/go-loom/plugin/types"
+	"github.com/loomnetwork/go-loom/vm"
+)
+
+// BatchCall is one call of a JSON-RPC batch.
+type BatchCall struct {
+	Method string
+	Params map[string]interface{}
+
+	// Result, if non-nil, is where the result of the call is decoded.
+	Result interface{}
+
+	// Err is the error of the call once the batch is done.
+	Err error
+}
+
+// CallBatch sends calls in a single JSON-RPC batch request, and decodes the
+// result or error of each into it. The error of the batch as a whole, such
+// as a transport error, is returned and set on every call.
+func (c *JSONRPCClient) CallBatch(ctx context.Context, calls []*BatchCall) (err error) {
+	method := "batch"
+	if len(calls) > 0 {
+		method = calls[0].Method
+	}
+	trace := c.startTrace(method)
+	defer func() {
+		trace.done(err)
+		if err != nil {
+			for _, call := range calls {
+				call.Err = err
+			}
+		}
+	}()
+
+	// The IDs of the calls are their index, to match them with the
+	// responses, which may come in any order.
+	rpcReqs := make([]RPCRequest, len(calls))
+	for i, call := range calls {
+		paramsBytes, err := json.Marshal(call.Params)
+		if err != nil {
+			return err
+		}
+		rpcReqs[i] = NewRPCRequest(call.Method, paramsBytes, strconv.Itoa(i))
+	}
+	reqBytes, err := json.Marshal(rpcReqs)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.host, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "text/json")
+	resp, err := c.doReq(trace.withTrace(req.WithContext(ctx)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResps []RPCResponse
+	if err := json.Unmarshal(respBytes, &rpcResps); err != nil {
+		// The batch as a whole was rejected with a single response.
+		var rpcResp RPCResponse
+		if json.Unmarshal(respBytes, &rpcResp) == nil && rpcResp.Error != nil {
+			return &ResponseError{RPCError: rpcResp.Error}
+		}
+		return &DecodeError{What: "rpc batch response", Err: err}
+	}
+	done := make([]bool, len(calls))
+	for _, rpcResp := range rpcResps {
+		i, err := strconv.Atoi(rpcResp.ID)
+		if err != nil || i < 0 || i >= len(calls) || done[i] {
+			continue
+		}
+		done[i] = true
+		call := calls[i]
+		if rpcResp.Error != nil {
+			call.Err = &ResponseError{RPCError: rpcResp.Error}
+		} else if call.Result != nil {
+			if err := json.Unmarshal(rpcResp.Result, call.Result); err != nil {
+				call.Err = &DecodeError{What: "rpc response result", Err: err}
+			}
+		}
+	}
+	for i, call := range calls {
+		if !done[i] {
+			call.Err = &DecodeError{What: "rpc batch response", Err: fmt.Errorf("no response to call %d", i)}
+		}
+	}
+	return nil
+}
+
+// BatchResult is the outcome of one tx or query of a batch.
+type BatchResult struct {
+	Data []byte
+
+	// Commit identifies the tx, for the txs of CommitTxBatch.
+	Commit CommitInfo
+
+	Err error
+}
+
+// CommitTxBatch signs txs, the marshalled txs of CraftCallTx, with
+// consecutive nonces and commits them in a single JSON-RPC batch. It
+// returns the error of the batch as a whole, or the outcome of every tx.
+func (c *DAppChainRPCClient) CommitTxBatch(ctx context.Context, signer auth.Signer, txs [][]byte) ([]BatchResult, error) {
+	nonce, err := c.GetNonce(ctx, signer)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]*BatchCall, len(txs))
+	rs := make([]BroadcastTxCommitResult, len(txs))
+	for i, txBytes := range txs {
+		signedTxBytes, err := signTx(signer, txBytes, nonce+1+uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		calls[i] = &BatchCall{
+			Method: "broadcast_tx_commit",
+			Params: map[string]interface{}{"tx": signedTxBytes},
+			Result: &rs[i],
+		}
+	}
+	if err := c.txClient.CallBatch(ctx, calls); err != nil {
+		return nil, err
+	}
+	results := make([]BatchResult, len(txs))
+	for i, call := range calls {
+		results[i].Commit.Nonce = nonce + 1 + uint64(i)
+		if call.Err != nil {
+			results[i].Err = call.Err
+			continue
+		}
+		results[i].Commit.Hash, results[i].Commit.Height = rs[i].Hash, rs[i].Height
+		results[i].Data = rs[i].DeliverTx.Data
+		results[i].Err = rs[i].err()
+	}
+	return results, nil
+}
+
+// QueryBatch queries a contract with every query in a single JSON-RPC
+// batch. It returns the error of the batch as a whole, or the outcome of
+// every query.
+func (c *DAppChainRPCClient) QueryBatch(ctx context.Context, caller loom.Address, contractAddr loom.LocalAddress, queries []proto.Message) ([]BatchResult, error) {
+	calls := make([]*BatchCall, len(queries))
+	rs := make([][]byte, len(queries))
+	for i, query := range queries {
+		queryBytes, err := proto.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+		calls[i] = &BatchCall{
+			Method: "query",
+			Params: map[string]interface{}{
+				"caller":   caller.String(),
+				"contract": contractAddr.String(),
+				"query":    queryBytes,
+				"vmType":   vm.VMType_PLUGIN,
+			},
+			Result: &rs[i],
+		}
+	}
+	if err := c.queryClient.CallBatch(ctx, calls); err != nil {
+		return nil, err
+	}
+	results := make([]BatchResult, len(queries))
+	for i, call := range calls {
+		results[i].Data, results[i].Err = rs[i], call.Err
+	}
+	return results, nil
+}
+
+// ContractCall is one call of a contract method in a batch.
+type ContractCall struct {
+	Method string
+	Args   proto.Message
+
+	// Result, if non-nil, is where the return value of the method is
+	// decoded.
+	Result proto.Message
+
+	// Commit identifies the tx of a call made by CallBatch.
+	Commit CommitInfo
+
+	// Err is the error of the call once the batch is done.
+	Err error
+}
+
+// CallBatch calls the methods of calls in txs committed in a single JSON-RPC
+// batch. The error of the batch as a whole is returned and set on every
+// call.
+func (c *Contract) CallBatch(ctx context.Context, calls []*ContractCall, signer auth.Signer) error {
+	txs := make([][]byte, len(calls))
+	for i, call := range calls {
+		txBytes, err := c.CraftCallTx(call.Method, call.Args, signer)
+		if err != nil {
+			return setBatchErr(calls, err)
+		}
+		txs[i] = txBytes
+	}
+	results, err := c.client.CommitTxBatch(ctx, signer, txs)
+	if err != nil {
+		return setBatchErr(calls, err)
+	}
+	for i, call := range calls {
+		call.Commit, call.Err = results[i].Commit, results[i].Err
+		if call.Err == nil && call.Result != nil && len(results[i].Data) > 0 {
+			response := &ptypes.Response{}
+			if err := proto.Unmarshal(results[i].Data, response); err == nil {
+				call.Err = proto.Unmarshal(response.Body, call.Result)
+			}
+		}
+	}
+	return nil
+}
+
+// StaticCallBatch calls the read-only methods of calls in queries sent in a
+// single JSON-RPC batch. The error of the batch as a whole is returned and
+// set on every call.
+func (c *Contract) StaticCallBatch(ctx context.Context, calls []*ContractCall, caller loom.Address) error {
+	queries := make([]proto.Message, len(calls))
+	for i, call := range calls {
+		argsBytes, err := proto.Marshal(call.Args)
+		if err != nil {
+			return setBatchErr(calls, err)
+		}
+		queries[i] = &plugin.ContractMethodCall{
+			Method: call.Method,
+			Args:   argsBytes,
+		}
+	}
+	results, err := c.client.QueryBatch(ctx, caller, c.Address.Local, queries)
+	if err != nil {
+		return setBatchErr(calls, err)
+	}
+	for i, call := range calls {
+		call.Err = results[i].Err
+		if call.Err == nil && call.Result != nil && len(results[i].Data) > 0 {
+			call.Err = proto.Unmarshal(results[i].Data, call.Result)
+		}
+	}
+	return nil
+}
+
+// CallBatch calls the methods of calls in txs committed in a single JSON-RPC
+// batch.
+func (contract *ContractClient) CallBatch(ctx context.Context, calls []*ContractCall) error {
+	return contract.c.CallBatch(ctx, calls, contract.signer)
+}
+
+// StaticCallBatch calls the read-only methods of calls in queries sent in a
+// single JSON-RPC batch.
+func (contract *ContractClient) StaticCallBatch(ctx context.Context, calls []*ContractCall) error {
+	return contract.c.StaticCallBatch(ctx, calls, loom.RootAddress(contract.chainID))
+}
+
+func setBatchErr(calls []*ContractCall, err error) error {
+	for _, call := range calls {
+		call.Err = err
+	}
+	return err
+}
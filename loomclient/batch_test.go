@@ -0,0 +1,186 @@
+package loomclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// batchServer replies to every JSON-RPC batch with resp, after checking
+// that it has a call of method with the ID of its index for each of calls.
+func batchServer(t *testing.T, calls int, resp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var reqs []struct {
+			Method string `json:"method"`
+			ID     string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			t.Errorf("batch request %s: %v", body, err)
+		}
+		if len(reqs) != calls {
+			t.Errorf("batch of %d calls, want %d", len(reqs), calls)
+		}
+		for i, req := range reqs {
+			if req.Method != "echo" || req.ID != fmt.Sprint(i) {
+				t.Errorf("call %d is %s with ID %q, want echo with ID %d", i, req.Method, req.ID, i)
+			}
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+type echoResult struct {
+	V int `json:"v"`
+}
+
+// result returns the response to the call with the JSON ID id.
+func result(id string, v int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"v":%d}}`, id, v)
+}
+
+func rpcError(id string, msg string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32603,"message":%q}}`, id, msg)
+}
+
+func batch(resps ...string) string {
+	return "[" + strings.Join(resps, ",") + "]"
+}
+
+func TestCallBatch(t *testing.T) {
+	tests := []struct {
+		name string
+		resp string
+		// want is the result of each call, or the start of its error.
+		want []interface{}
+	}{
+		{
+			name: "in order",
+			resp: batch(result(`"0"`, 10), result(`"1"`, 11), result(`"2"`, 12)),
+			want: []interface{}{10, 11, 12},
+		},
+		{
+			name: "reordered",
+			resp: batch(result(`"2"`, 12), result(`"0"`, 10), result(`"1"`, 11)),
+			want: []interface{}{10, 11, 12},
+		},
+		{
+			name: "missing response",
+			resp: batch(result(`"2"`, 12), result(`"0"`, 10)),
+			want: []interface{}{10, "error unmarshalling rpc batch response: no response to call 1", 12},
+		},
+		{
+			name: "duplicate ID",
+			resp: batch(result(`"0"`, 10), result(`"0"`, 99), result(`"2"`, 12)),
+			want: []interface{}{10, "error unmarshalling rpc batch response: no response to call 1", 12},
+		},
+		{
+			name: "bad IDs",
+			resp: batch(result(`"x"`, 99), result(`"-1"`, 99), result(`"3"`, 99), result(`""`, 99), result(`"1"`, 11)),
+			want: []interface{}{
+				"error unmarshalling rpc batch response: no response to call 0",
+				11,
+				"error unmarshalling rpc batch response: no response to call 2",
+			},
+		},
+		{
+			name: "rpc errors",
+			resp: batch(result(`"0"`, 10), rpcError(`"1"`, "tx already exists"), rpcError(`"2"`, "invalid nonce")),
+			want: []interface{}{10, "Response error: ", "Response error: "},
+		},
+		{
+			name: "bad result",
+			resp: batch(result(`"0"`, 10), `{"jsonrpc":"2.0","id":"1","result":"v"}`, result(`"2"`, 12)),
+			want: []interface{}{10, "error unmarshalling rpc response result", 12},
+		},
+	}
+	for _, tt := range tests {
+		srv := batchServer(t, 3, tt.resp)
+		c := NewJSONRPCClient(&http.Client{}, srv.URL)
+		calls := make([]*BatchCall, 3)
+		results := make([]echoResult, 3)
+		for i := range calls {
+			calls[i] = &BatchCall{Method: "echo", Params: map[string]interface{}{"v": i}, Result: &results[i]}
+		}
+		if err := c.CallBatch(context.Background(), calls); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			srv.Close()
+			continue
+		}
+		for i, want := range tt.want {
+			switch want := want.(type) {
+			case int:
+				if calls[i].Err != nil || results[i].V != want {
+					t.Errorf("%s: call %d = %d, %v, want %d", tt.name, i, results[i].V, calls[i].Err, want)
+				}
+			case string:
+				if calls[i].Err == nil || !strings.HasPrefix(calls[i].Err.Error(), want) {
+					t.Errorf("%s: call %d error = %v, want %q", tt.name, i, calls[i].Err, want)
+				}
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestCallBatchRPCError(t *testing.T) {
+	srv := batchServer(t, 2, batch(result(`"0"`, 10), rpcError(`"1"`, "invalid nonce")))
+	defer srv.Close()
+	c := NewJSONRPCClient(&http.Client{}, srv.URL)
+	calls := []*BatchCall{{Method: "echo"}, {Method: "echo"}}
+	if err := c.CallBatch(context.Background(), calls); err != nil {
+		t.Fatal(err)
+	}
+	rerr, ok := calls[1].Err.(*ResponseError)
+	if !ok || rerr.RPCError.Message != "invalid nonce" || rerr.RPCError.Code != -32603 {
+		t.Errorf("call error = %#v, want the RPC error of the call", calls[1].Err)
+	}
+	if ErrorCategory(calls[1].Err) != ErrCategoryRPC {
+		t.Errorf("category = %s, want %s", ErrorCategory(calls[1].Err), ErrCategoryRPC)
+	}
+	if calls[0].Err != nil {
+		t.Errorf("the error of a call failed the other: %v", calls[0].Err)
+	}
+}
+
+func TestCallBatchRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		resp string
+		err  string
+	}{
+		{"rejected", rpcError(`null`, "batch too large"), "Response error: "},
+		{"not json", "Bad Gateway", "error unmarshalling rpc batch response"},
+		{"object without error", result(`"0"`, 10), "error unmarshalling rpc batch response"},
+		{"numeric IDs", batch(result(`0`, 10), result(`1`, 11)), "error unmarshalling rpc batch response"},
+	}
+	for _, tt := range tests {
+		srv := batchServer(t, 2, tt.resp)
+		c := NewJSONRPCClient(&http.Client{}, srv.URL)
+		calls := []*BatchCall{{Method: "echo"}, {Method: "echo"}}
+		err := c.CallBatch(context.Background(), calls)
+		srv.Close()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			continue
+		}
+		// The error of the batch is the error of every call.
+		for i, call := range calls {
+			if call.Err != err {
+				t.Errorf("%s: call %d error = %v, want %v", tt.name, i, call.Err, err)
+			}
+		}
+	}
+
+	srv := batchServer(t, 2, rpcError(`null`, "batch too large"))
+	defer srv.Close()
+	err := NewJSONRPCClient(&http.Client{}, srv.URL).CallBatch(context.Background(), []*BatchCall{{Method: "echo"}, {Method: "echo"}})
+	if rerr, ok := err.(*ResponseError); !ok || rerr.RPCError.Message != "batch too large" {
+		t.Errorf("rejected batch error = %#v, want its RPC error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package loomclient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"reflect"
@@ -36,7 +37,7 @@ func NewContract(client *DAppChainRPCClient, contractAddr loom.LocalAddress) *Co
 	}
 }
 
-func (c *Contract) Call(method string, args proto.Message, signer auth.Signer, result interface{}) (interface{}, error) {
+func (c *Contract) Call(ctx context.Context, method string, args proto.Message, signer auth.Signer, result interface{}) (interface{}, error) {
 	if result != nil && !reflect.TypeOf(result).Implements(typeOfPBMessage) {
 		return nil, errors.New("Contract.Call result parameter must be a protobuf")
 	}
@@ -79,7 +80,7 @@ func (c *Contract) Call(method string, args proto.Message, signer auth.Signer, r
 	if err != nil {
 		return nil, err
 	}
-	resultBytes, err := c.client.CommitTx(signer, &types.Transaction{
+	resultBytes, err := c.client.CommitTx(ctx, signer, &types.Transaction{
 		Id:   2,
 		Data: msgTxBytes,
 	})
@@ -99,7 +100,7 @@ func (c *Contract) Call(method string, args proto.Message, signer auth.Signer, r
 	return nil, nil
 }
 
-func (c *Contract) StaticCall(method string, args proto.Message, caller loom.Address, result interface{}) (interface{}, error) {
+func (c *Contract) StaticCall(ctx context.Context, method string, args proto.Message, caller loom.Address, result interface{}) (interface{}, error) {
 	if result == nil || !reflect.TypeOf(result).Implements(typeOfPBMessage) {
 		return nil, errors.New("Contract.StaticCall result parameter must be a protobuf")
 	}
@@ -111,7 +112,7 @@ func (c *Contract) StaticCall(method string, args proto.Message, caller loom.Add
 		Method: method,
 		Args:   argsBytes,
 	}
-	resultBytes, err := c.client.Query(caller, c.Address.Local, methodCall)
+	resultBytes, err := c.client.Query(ctx, caller, c.Address.Local, methodCall)
 	if err != nil {
 		return nil, err
 	}
@@ -196,15 +197,21 @@ func (c *Contract) SignTxBytes(txBytes []byte, nonce uint64, signer auth.Signer)
 	// 	"tx": signedTxBytes,
 	// }
 
+	if c.client.fastJSON {
+		return marshalFast(params)
+	}
 	return json.Marshal(params)
 }
 
 func (c *Contract) CraftRPCReqBytes(method string, txParamsBytes []byte) ([]byte, error) {
 	rpcReq := NewRPCRequest(method, txParamsBytes, c.client.getNextRequestID())
+	if c.client.fastJSON {
+		return marshalFast(rpcReq)
+	}
 	return json.Marshal(rpcReq)
 }
 
-func (c *Contract) CallRaw(tx []byte) error {
-	return c.client.CommitTxRaw(tx)
+func (c *Contract) CallRaw(ctx context.Context, tx []byte) error {
+	return c.client.CommitTxRaw(ctx, tx)
 	// return err
 }
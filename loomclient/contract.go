@@ -1,6 +1,7 @@
 package loomclient
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"strings"
@@ -18,14 +19,14 @@ type ContractClient struct {
 	rpcClient *DAppChainRPCClient
 }
 
-func NewContractClient(contractAddr, chainID string, signer auth.Signer, rpcClient *DAppChainRPCClient) (*ContractClient, error) {
+func NewContractClient(ctx context.Context, contractAddr, chainID string, signer auth.Signer, rpcClient *DAppChainRPCClient) (*ContractClient, error) {
 	contract := &ContractClient{
 		chainID:   chainID,
 		signer:    signer,
 		rpcClient: rpcClient,
 	}
 
-	addr, err := contract.resolveAddress(contractAddr)
+	addr, err := contract.resolveAddress(ctx, contractAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +39,8 @@ func (contract *ContractClient) GetContract() *Contract {
 	return contract.c
 }
 
-func (contract *ContractClient) Call(method string, params proto.Message, result interface{}) error {
-	_, err := contract.c.Call(method, params, contract.signer, result)
+func (contract *ContractClient) Call(ctx context.Context, method string, params proto.Message, result interface{}) error {
+	_, err := contract.c.Call(ctx, method, params, contract.signer, result)
 	return err
 }
 
@@ -51,10 +52,20 @@ func (contract *ContractClient) GetSigner() auth.Signer {
 	return contract.signer
 }
 
-// func (contract *ContractClient) StaticCall() {
-// 	_, err := contract.c.StaticCall(method, params, loom.RootAddress(contract.chainID), result)
-// 	return err
-// }
+// GetCallerAddress returns the address txs are sent from.
+func (contract *ContractClient) GetCallerAddress() loom.Address {
+	return loom.Address{
+		ChainID: contract.chainID,
+		Local:   loom.LocalAddressFromPublicKey(contract.signer.PublicKey()),
+	}
+}
+
+// StaticCall calls a read-only method of the contract, decoding its return
+// value into result.
+func (contract *ContractClient) StaticCall(ctx context.Context, method string, params proto.Message, result proto.Message) error {
+	_, err := contract.c.StaticCall(ctx, method, params, loom.RootAddress(contract.chainID), result)
+	return err
+}
 
 func (contract *ContractClient) parseAddress(s string) (loom.Address, error) {
 	addr, err := loom.ParseAddress(s)
@@ -70,11 +81,11 @@ func (contract *ContractClient) parseAddress(s string) (loom.Address, error) {
 	return loom.Address{ChainID: contract.chainID, Local: loom.LocalAddress(b)}, nil
 }
 
-func (contract *ContractClient) resolveAddress(s string) (loom.Address, error) {
+func (contract *ContractClient) resolveAddress(ctx context.Context, s string) (loom.Address, error) {
 	contractAddr, err := contract.parseAddress(s)
 	if err != nil {
 		// if address invalid, try to resolve it using registry
-		contractAddr, err = contract.rpcClient.Resolve(s)
+		contractAddr, err = contract.rpcClient.Resolve(ctx, s)
 		if err != nil {
 			return loom.Address{}, err
 		}
@@ -1,11 +1,12 @@
 package loomclient
 
 import (
+	"context"
 	"encoding/hex"
-	"errors"
 	"net/http"
 	"net/http/httptrace"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/loomnetwork/go-loom"
@@ -29,6 +30,26 @@ type BroadcastTxCommitResult struct {
 	Height    int64           `json:"height"`
 }
 
+// err returns a TxError if the tx failed CheckTx or DeliverTx.
+func (r *BroadcastTxCommitResult) err() error {
+	if r.CheckTx.Code != 0 {
+		return &TxError{Phase: ErrCategoryCheckTx, Code: r.CheckTx.Code, Log: r.CheckTx.Error}
+	}
+	if r.DeliverTx.Code != 0 {
+		return &TxError{Phase: ErrCategoryDeliverTx, Code: r.DeliverTx.Code, Log: r.DeliverTx.Error}
+	}
+	return nil
+}
+
+// CommitInfo identifies the last tx committed by a client.
+type CommitInfo struct {
+	// Nonce is the sequence number the tx was signed with, if it was signed
+	// by the client.
+	Nonce  uint64
+	Hash   string
+	Height int64
+}
+
 // Implements the DAppChainClient interface
 type DAppChainRPCClient struct {
 	chainID       string
@@ -37,13 +58,15 @@ type DAppChainRPCClient struct {
 	txClient      *JSONRPCClient
 	queryClient   *JSONRPCClient
 	nextRequestID uint64
+	lastCommit    CommitInfo
+	fastJSON      bool
 }
 
 // NewDAppChainRPCClient creates a new dumb client that can be used to commit txs and query contract
 // state via RPC.
-// URI parameters should be specified as "tcp://<host>:<port>", writeURI the host that txs will be
-// submitted to (port 46657 by default), readURI is the host that will be queried for current app
-// state (47000 by default).
+// URI parameters should be specified as "tcp://<host>:<port>", "http(s)://<host>:<port>/<path>" or
+// "unix:///<socket>?path=/<path>", writeURI the host that txs will be submitted to (port 46657 by
+// default), readURI is the host that will be queried for current app state (47000 by default).
 func NewDAppChainRPCClient(httpclient *http.Client, chainID, writeURI, readURI string) *DAppChainRPCClient {
 	return &DAppChainRPCClient{
 		chainID:       chainID,
@@ -55,47 +78,79 @@ func NewDAppChainRPCClient(httpclient *http.Client, chainID, writeURI, readURI s
 	}
 }
 
+// UseTrace installs trace on every call to the tx endpoint.
+//
+// Deprecated: the trace is shared by all calls, use UseTracer to trace each
+// call to both endpoints separately.
 func (c *DAppChainRPCClient) UseTrace(trace *httptrace.ClientTrace) {
 	c.txClient.UseTrace(trace)
 }
 
+// UseTracer installs tracer on the calls to both the tx and the query
+// endpoints, including the nonce lookups made by CommitTx.
+func (c *DAppChainRPCClient) UseTracer(tracer Tracer) {
+	c.txClient.UseTracer(EndpointTx, tracer)
+	c.queryClient.UseTracer(EndpointQuery, tracer)
+}
+
+// UseFastJSON switches the raw request path, the SignTxBytes,
+// CraftRPCReqBytes and CallRaw of its contracts, between encoding/json and
+// the easyjson marshalers with pooled buffers. With fast JSON on, CallRaw
+// also decodes the response in one pass and makes its request with fewer
+// allocations; most of those left are made by net/http.
+func (c *DAppChainRPCClient) UseFastJSON(on bool) {
+	c.fastJSON = on
+	c.txClient.fastJSON = on
+	c.queryClient.fastJSON = on
+}
+
+// getNextRequestID returns the ID of a new request. It is safe to call
+// concurrently, so that queries can be made from several goroutines.
 func (c *DAppChainRPCClient) getNextRequestID() string {
-	id := strconv.FormatUint(c.nextRequestID, 10)
-	c.nextRequestID++
-	return id
+	return strconv.FormatUint(atomic.AddUint64(&c.nextRequestID, 1)-1, 10)
 }
 
 func (c *DAppChainRPCClient) GetChainID() string {
 	return c.chainID
 }
 
-func (c *DAppChainRPCClient) GetNonce(signer auth.Signer) (uint64, error) {
+// LastCommit returns the identifiers of the last tx committed by the client.
+// The hash and height are empty if the node didn't reply with a result.
+func (c *DAppChainRPCClient) LastCommit() CommitInfo {
+	return c.lastCommit
+}
+
+// Status returns the status of the node that txs are submitted to.
+func (c *DAppChainRPCClient) Status(ctx context.Context) (*NodeStatus, error) {
+	var r NodeStatus
+	if err := c.txClient.Call(ctx, "status", map[string]interface{}{}, c.getNextRequestID(), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *DAppChainRPCClient) GetNonce(ctx context.Context, signer auth.Signer) (uint64, error) {
 	params := map[string]interface{}{
 		"key": hex.EncodeToString(signer.PublicKey()),
 	}
 	var r uint64
-	err := c.queryClient.Call("nonce", params, c.getNextRequestID(), &r)
+	err := c.queryClient.Call(ctx, "nonce", params, c.getNextRequestID(), &r)
 	return r, err
 }
 
-func (c *DAppChainRPCClient) CommitTx(signer auth.Signer, tx proto.Message) ([]byte, error) {
+func (c *DAppChainRPCClient) CommitTx(ctx context.Context, signer auth.Signer, tx proto.Message) ([]byte, error) {
 	// TODO: signing & noncing should be handled by middleware
-	nonce, err := c.GetNonce(signer)
+	c.lastCommit = CommitInfo{}
+	nonce, err := c.GetNonce(ctx, signer)
 	if err != nil {
 		return nil, err
 	}
+	c.lastCommit.Nonce = nonce + 1
 	txBytes, err := proto.Marshal(tx)
 	if err != nil {
 		return nil, err
 	}
-	nonceTxBytes, err := proto.Marshal(&auth.NonceTx{
-		Inner:    txBytes,
-		Sequence: nonce + 1,
-	})
-	if err != nil {
-		return nil, err
-	}
-	signedTxBytes, err := proto.Marshal(auth.SignTx(signer, nonceTxBytes))
+	signedTxBytes, err := signTx(signer, txBytes, nonce+1)
 	if err != nil {
 		return nil, err
 	}
@@ -103,44 +158,39 @@ func (c *DAppChainRPCClient) CommitTx(signer auth.Signer, tx proto.Message) ([]b
 		"tx": signedTxBytes,
 	}
 	var r BroadcastTxCommitResult
-	if err = c.txClient.Call("broadcast_tx_commit", params, c.getNextRequestID(), &r); err != nil {
+	if err = c.txClient.Call(ctx, "broadcast_tx_commit", params, c.getNextRequestID(), &r); err != nil {
 		return nil, err
 	}
-	if r.CheckTx.Code != 0 {
-		if len(r.CheckTx.Error) != 0 {
-			return nil, errors.New(r.CheckTx.Error)
-		}
-		return nil, errors.New("CheckTx failed")
-	}
-	if r.DeliverTx.Code != 0 {
-		if len(r.DeliverTx.Error) != 0 {
-			return nil, errors.New(r.DeliverTx.Error)
-		}
-		return nil, errors.New("DeliverTx failed")
+	c.lastCommit.Hash, c.lastCommit.Height = r.Hash, r.Height
+	if err := r.err(); err != nil {
+		return nil, err
 	}
 	return r.DeliverTx.Data, nil
 }
 
-func (c *DAppChainRPCClient) CommitTxRaw(txBytes []byte) error {
-	var r BroadcastTxCommitResult
-	return c.txClient.CallRaw(txBytes, &r)
-	if r.CheckTx.Code != 0 {
-		if len(r.CheckTx.Error) != 0 {
-			return errors.New(r.CheckTx.Error)
-		}
-		return errors.New("CheckTx failed")
-	}
-	if r.DeliverTx.Code != 0 {
-		if len(r.DeliverTx.Error) != 0 {
-			return errors.New(r.DeliverTx.Error)
-		}
-		return errors.New("DeliverTx failed")
+// signTx returns the signed tx of txBytes with nonce.
+func signTx(signer auth.Signer, txBytes []byte, nonce uint64) ([]byte, error) {
+	nonceTxBytes, err := proto.Marshal(&auth.NonceTx{
+		Inner:    txBytes,
+		Sequence: nonce,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return proto.Marshal(auth.SignTx(signer, nonceTxBytes))
+}
 
-	return nil
+func (c *DAppChainRPCClient) CommitTxRaw(ctx context.Context, txBytes []byte) error {
+	c.lastCommit = CommitInfo{}
+	var r BroadcastTxCommitResult
+	if err := c.txClient.CallRaw(ctx, txBytes, &r); err != nil {
+		return err
+	}
+	c.lastCommit.Hash, c.lastCommit.Height = r.Hash, r.Height
+	return r.err()
 }
 
-func (c *DAppChainRPCClient) Query(caller loom.Address, contractAddr loom.LocalAddress, query proto.Message) ([]byte, error) {
+func (c *DAppChainRPCClient) Query(ctx context.Context, caller loom.Address, contractAddr loom.LocalAddress, query proto.Message) ([]byte, error) {
 	queryBytes, err := proto.Marshal(query)
 	if err != nil {
 		return nil, err
@@ -152,18 +202,18 @@ func (c *DAppChainRPCClient) Query(caller loom.Address, contractAddr loom.LocalA
 		"vmType":   vm.VMType_PLUGIN,
 	}
 	var r []byte
-	if err = c.queryClient.Call("query", params, c.getNextRequestID(), &r); err != nil {
+	if err = c.queryClient.Call(ctx, "query", params, c.getNextRequestID(), &r); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
-func (c *DAppChainRPCClient) Resolve(name string) (loom.Address, error) {
+func (c *DAppChainRPCClient) Resolve(ctx context.Context, name string) (loom.Address, error) {
 	params := map[string]interface{}{
 		"name": name,
 	}
 	var addrStr string
-	if err := c.queryClient.Call("resolve", params, c.getNextRequestID(), &addrStr); err != nil {
+	if err := c.queryClient.Call(ctx, "resolve", params, c.getNextRequestID(), &addrStr); err != nil {
 		return loom.Address{}, err
 	}
 	return loom.ParseAddress(addrStr)
@@ -173,19 +223,19 @@ func (c *DAppChainRPCClient) Resolve(name string) (loom.Address, error) {
 // Gives an error for non-EVM contracts.
 // contract - address of the contract in the form of a string. (Use loom.Address.String() to convert)
 // return []byte - runtime bytecode of the contract.
-func (c *DAppChainRPCClient) GetCode(contract string) ([]byte, error) {
+func (c *DAppChainRPCClient) GetCode(ctx context.Context, contract string) ([]byte, error) {
 	params := map[string]interface{}{
 		"contract": contract,
 	}
 
 	var bytecode []byte
-	if err := c.queryClient.Call("getcode", params, c.getNextRequestID(), &bytecode); err != nil {
+	if err := c.queryClient.Call(ctx, "getcode", params, c.getNextRequestID(), &bytecode); err != nil {
 		return []byte{}, err
 	}
 	return bytecode, nil
 }
 
-func (c *DAppChainRPCClient) QueryEvm(caller loom.Address, contractAddr loom.LocalAddress, query []byte) ([]byte, error) {
+func (c *DAppChainRPCClient) QueryEvm(ctx context.Context, caller loom.Address, contractAddr loom.LocalAddress, query []byte) ([]byte, error) {
 	params := map[string]interface{}{
 		"caller":   caller.String(),
 		"contract": contractAddr.String(),
@@ -193,18 +243,18 @@ func (c *DAppChainRPCClient) QueryEvm(caller loom.Address, contractAddr loom.Loc
 		"vmType":   vm.VMType_EVM,
 	}
 	var r []byte
-	if err := c.queryClient.Call("query", params, c.getNextRequestID(), &r); err != nil {
+	if err := c.queryClient.Call(ctx, "query", params, c.getNextRequestID(), &r); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
-func (c *DAppChainRPCClient) GetEvmTxReceipt(txHash []byte) (vm.EvmTxReceipt, error) {
+func (c *DAppChainRPCClient) GetEvmTxReceipt(ctx context.Context, txHash []byte) (vm.EvmTxReceipt, error) {
 	params := map[string]interface{}{
 		"txHash": txHash,
 	}
 	var r []byte
-	if err := c.queryClient.Call("txreceipt", params, c.getNextRequestID(), &r); err != nil {
+	if err := c.queryClient.Call(ctx, "txreceipt", params, c.getNextRequestID(), &r); err != nil {
 		return vm.EvmTxReceipt{}, err
 	}
 	var receipt vm.EvmTxReceipt
@@ -214,6 +264,7 @@ func (c *DAppChainRPCClient) GetEvmTxReceipt(txHash []byte) (vm.EvmTxReceipt, er
 }
 
 func (c *DAppChainRPCClient) CommitDeployTx(
+	ctx context.Context,
 	from loom.Address,
 	signer auth.Signer,
 	vmType vm.VMType,
@@ -240,10 +291,11 @@ func (c *DAppChainRPCClient) CommitDeployTx(
 		Id:   1,
 		Data: msgBytes,
 	}
-	return c.CommitTx(signer, tx)
+	return c.CommitTx(ctx, signer, tx)
 }
 
 func (c *DAppChainRPCClient) CommitCallTx(
+	ctx context.Context,
 	caller loom.Address,
 	contract loom.Address,
 	signer auth.Signer,
@@ -271,5 +323,5 @@ func (c *DAppChainRPCClient) CommitCallTx(
 		Id:   2,
 		Data: msgBytes,
 	}
-	return c.CommitTx(signer, tx)
+	return c.CommitTx(ctx, signer, tx)
 }
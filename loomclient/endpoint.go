@@ -0,0 +1,71 @@
+package loomclient
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/url"
+	"sync"
+)
+
+// Endpoints are given as URLs of one of these forms:
+//
+//	http://<host>:<port>/<path>
+//	https://<host>:<port>/<path>
+//	tcp://<host>:<port>/<path>     (plain HTTP, like http://)
+//	unix:///<socket>?path=/<path>  (HTTP over the unix socket of a co-located node)
+//
+// The requests to tcp:// and unix:// endpoints are sent as HTTP requests
+// to a URL rewritten by requestURL, that DialEndpoint maps back to the
+// endpoint.
+
+// unixSockets maps the host:port of the rewritten URL of unix:// endpoints
+// to the path of their socket.
+var unixSockets = struct {
+	sync.RWMutex
+	paths map[string]string
+}{paths: make(map[string]string)}
+
+// requestURL returns the URL the requests to endpoint are posted to.
+func requestURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return endpoint, nil
+	case "tcp":
+		u.Scheme = "http"
+		return u.String(), nil
+	case "unix":
+		if u.Path == "" {
+			return "", fmt.Errorf("no socket path in %s", endpoint)
+		}
+		h := fnv.New32a()
+		h.Write([]byte(u.Path))
+		host := fmt.Sprintf("unix-%08x", h.Sum32())
+		unixSockets.Lock()
+		unixSockets.paths[host+":80"] = u.Path
+		unixSockets.Unlock()
+		r := &url.URL{Scheme: "http", Host: host, Path: u.Query().Get("path")}
+		return r.String(), nil
+	}
+	return "", fmt.Errorf("unsupported scheme %q in %s, use http, https, tcp or unix", u.Scheme, endpoint)
+}
+
+// DialEndpoint dials addr, or the socket of the unix:// endpoint it is the
+// rewritten address of. It is the dial function of the transports of
+// clients that don't set one, and of transports shared by the clients of
+// several endpoints.
+func DialEndpoint(ctx context.Context, network, addr string) (net.Conn, error) {
+	unixSockets.RLock()
+	path, ok := unixSockets.paths[addr]
+	unixSockets.RUnlock()
+	var dialer net.Dialer
+	if ok {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
@@ -0,0 +1,121 @@
+package loomclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		err      string
+	}{
+		{endpoint: "http://localhost:46658/rpc", want: "http://localhost:46658/rpc"},
+		{endpoint: "https://node.example.com/query", want: "https://node.example.com/query"},
+		{endpoint: "tcp://localhost:46658/rpc", want: "http://localhost:46658/rpc"},
+		{endpoint: "tcp://10.0.0.1:46658", want: "http://10.0.0.1:46658"},
+		{endpoint: "unix://", err: "no socket path"},
+		{endpoint: "unix:?path=/rpc", err: "no socket path"},
+		{endpoint: "ws://localhost:46658/rpc", err: "unsupported scheme"},
+		{endpoint: "localhost:46658", err: "unsupported scheme"},
+		{endpoint: "", err: "unsupported scheme"},
+		{endpoint: "http://local host/rpc", err: "invalid character"},
+	}
+	for _, tt := range tests {
+		got, err := requestURL(tt.endpoint)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error = %v, want %q", tt.endpoint, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.endpoint, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: requestURL() = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestRequestURLUnix(t *testing.T) {
+	a, err := requestURL("unix:///var/run/a.sock?path=/rpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := requestURL("unix:///var/run/b.sock?path=/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua, _ := http.NewRequest("POST", a, nil)
+	ub, _ := http.NewRequest("POST", b, nil)
+	if ua.URL.Scheme != "http" || ua.URL.Path != "/rpc" || ub.URL.Path != "/query" {
+		t.Errorf("requestURL() = %s, %s, want http URLs with the paths /rpc and /query", a, b)
+	}
+	if ua.URL.Host == ub.URL.Host {
+		t.Errorf("the URLs of different sockets share the host %s", ua.URL.Host)
+	}
+	unixSockets.RLock()
+	path := unixSockets.paths[ua.URL.Host+":80"]
+	unixSockets.RUnlock()
+	if path != "/var/run/a.sock" {
+		t.Errorf("%s maps to socket %q, want /var/run/a.sock", ua.URL.Host, path)
+	}
+}
+
+func TestUnixEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loomclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "node.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if r.URL.Path != "/rpc" || req.Method != "status" {
+			t.Errorf("got %s %s, want status /rpc", req.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"1","result":{"node_info":"unix"}}`))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	for _, client := range []*http.Client{{}, {Transport: &http.Transport{}}} {
+		c := NewJSONRPCClient(client, "unix://"+socket+"?path=/rpc")
+		var status struct {
+			NodeInfo string `json:"node_info"`
+		}
+		if err := c.Call(context.Background(), "status", map[string]interface{}{}, "1", &status); err != nil {
+			t.Fatal(err)
+		}
+		if status.NodeInfo != "unix" {
+			t.Errorf("got status %+v, want node_info unix", status)
+		}
+	}
+}
+
+func TestInvalidEndpoint(t *testing.T) {
+	c := NewJSONRPCClient(&http.Client{}, "ftp://localhost:46658/rpc")
+	var status json.RawMessage
+	if err := c.Call(context.Background(), "status", map[string]interface{}{}, "1", &status); err == nil {
+		t.Error("call to an invalid endpoint succeeded")
+	}
+}
@@ -0,0 +1,95 @@
+package loomclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Categories of errors returned by the client.
+const (
+	ErrCategoryTimeout   = "timeout"    // the request timed out
+	ErrCategoryTransport = "transport"  // the node couldn't be reached
+	ErrCategoryRPC       = "rpc"        // the node replied with a JSON-RPC error
+	ErrCategoryDecode    = "decode"     // the node's reply couldn't be decoded
+	ErrCategoryNonce     = "nonce"      // the tx was rejected for its nonce
+	ErrCategoryCheckTx   = "check_tx"   // the tx was rejected by CheckTx
+	ErrCategoryDeliverTx = "deliver_tx" // the tx failed in DeliverTx
+	ErrCategoryCancelled = "cancelled"  // the call's context was done
+	ErrCategoryOther     = "other"
+)
+
+// ResponseError is returned when the node replies with a JSON-RPC error.
+type ResponseError struct {
+	RPCError *RPCError
+}
+
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("Response error: %v", err.RPCError)
+}
+
+// DecodeError is returned when the node's reply can't be unmarshalled.
+type DecodeError struct {
+	What string
+	Err  error
+}
+
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("error unmarshalling %s: %v", err.What, err.Err)
+}
+
+// TxError is returned when a committed tx fails CheckTx or DeliverTx.
+type TxError struct {
+	// Phase is ErrCategoryCheckTx or ErrCategoryDeliverTx.
+	Phase string
+	Code  int32
+	Log   string
+}
+
+func (err *TxError) Error() string {
+	if len(err.Log) != 0 {
+		return err.Log
+	}
+	if err.Phase == ErrCategoryCheckTx {
+		return "CheckTx failed"
+	}
+	return "DeliverTx failed"
+}
+
+// ErrorCategory returns the category of an error returned by the client.
+func ErrorCategory(err error) string {
+	if isContextErr(err) {
+		return ErrCategoryCancelled
+	}
+	switch err := err.(type) {
+	case nil:
+		return ""
+	case *TxError:
+		log := strings.ToLower(err.Log)
+		if strings.Contains(log, "nonce") || strings.Contains(log, "sequence") {
+			return ErrCategoryNonce
+		}
+		return err.Phase
+	case *ResponseError:
+		return ErrCategoryRPC
+	case *DecodeError:
+		return ErrCategoryDecode
+	case net.Error:
+		if err.Timeout() {
+			return ErrCategoryTimeout
+		}
+		return ErrCategoryTransport
+	}
+	return ErrCategoryOther
+}
+
+// isContextErr reports whether err is the error of a done context, as
+// returned by the client or wrapped by net/http.
+func isContextErr(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
@@ -0,0 +1,191 @@
+package loomclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+//go:generate easyjson -no_std_marshalers rpc_client.go dapp_chain_client.go client.go
+
+// The types annotated //easyjson:json have easyjson marshalers generated in
+// the *_easyjson.go files, used instead of encoding/json by the raw request
+// path of clients with fast JSON on, see UseFastJSON. No MarshalJSON or
+// UnmarshalJSON methods are generated, so encoding/json is unaffected.
+
+// minPooledBuffer is the capacity of the pooled buffers requests are
+// encoded into, enough for a raw tx. maxPooledBuffer is the capacity above
+// which a buffer isn't put back in the pool, not to keep the memory of an
+// unusually large response.
+const (
+	minPooledBuffer = 1 << 10
+	maxPooledBuffer = 64 << 10
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// encoder is a pooled easyjson writer and the buffer it writes into.
+type encoder struct {
+	w   jwriter.Writer
+	buf []byte
+}
+
+var encoderPool = sync.Pool{
+	New: func() interface{} { return &encoder{buf: make([]byte, 0, minPooledBuffer)} },
+}
+
+// marshalFast encodes v with its easyjson marshaler into a pooled buffer,
+// and returns a copy of the encoding. easyjson.Marshal would allocate the
+// writer, and the chunks of its buffer, too small for a signed tx, on every
+// call. The copy is the one allocation left: the encoding is the caller's,
+// and a raw request is kept to be sent again when it is retried.
+func marshalFast(v easyjson.Marshaler) ([]byte, error) {
+	e := encoderPool.Get().(*encoder)
+	e.w = jwriter.Writer{}
+	e.w.Buffer.Buf = e.buf[:0]
+	v.MarshalEasyJSON(&e.w)
+	// An encoding that outgrew the buffer leaves it to the writer, and the
+	// encoder isn't reused.
+	outgrown := e.w.Size() > len(e.w.Buffer.Buf)
+	if !outgrown {
+		defer encoderPool.Put(e)
+	}
+	if e.w.Error != nil {
+		return nil, e.w.Error
+	}
+	if outgrown {
+		return e.w.BuildBytes()
+	}
+	return append([]byte(nil), e.w.Buffer.Buf...), nil
+}
+
+// rawTxResponse is the response to a raw tx, decoded in one pass: its
+// result is decoded straight into Result, rather than copied into the
+// json.RawMessage of an RPCResponse and decoded from there. The jsonrpc
+// version and the ID aren't kept.
+type rawTxResponse struct {
+	Result *BroadcastTxCommitResult
+	Error  *RPCError
+
+	// hasResult is whether the response has a result, and resultErr the
+	// error decoding it, if any.
+	hasResult bool
+	resultErr error
+}
+
+// UnmarshalEasyJSON decodes the response, and its result into Result if it
+// is set.
+func (out *rawTxResponse) UnmarshalEasyJSON(in *jlexer.Lexer) {
+	isTopLevel := in.IsStart()
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch {
+		case key == "result":
+			out.hasResult = true
+			if out.Result == nil || in.IsNull() {
+				in.SkipRecursive()
+				break
+			}
+			out.Result.UnmarshalEasyJSON(in)
+			out.resultErr = in.Error()
+		case key == "error" && !in.IsNull():
+			out.Error = new(RPCError)
+			out.Error.UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+
+// errNoResult is the error of a response with neither a result nor an error.
+var errNoResult = errors.New("no result")
+
+// decodeRawFast reads the response to a raw tx into a pooled buffer and
+// decodes it in one pass, with its result into result.
+func decodeRawFast(body io.Reader, result *BroadcastTxCommitResult) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+
+	// The lexer is used directly rather than through easyjson.Unmarshal,
+	// whose interface argument would move result to the heap.
+	rpcResp := rawTxResponse{Result: result}
+	in := jlexer.Lexer{Data: buf.Bytes()}
+	rpcResp.UnmarshalEasyJSON(&in)
+	err := in.Error()
+	switch {
+	case rpcResp.Error != nil:
+		return &ResponseError{RPCError: rpcResp.Error}
+	case rpcResp.resultErr != nil:
+		return &DecodeError{What: "rpc response result", Err: rpcResp.resultErr}
+	case err != nil:
+		return &DecodeError{What: "rpc response", Err: err}
+	case result != nil && !rpcResp.hasResult:
+		return &DecodeError{What: "rpc response result", Err: errNoResult}
+	}
+	return nil
+}
+
+// rawBody is the body of a raw request. http.NewRequest wraps a
+// bytes.Buffer body in an io.NopCloser and makes a GetBody closure for it,
+// three allocations; rawBody is its own io.ReadCloser and GetBody.
+type rawBody struct {
+	bytes.Reader
+	data []byte
+}
+
+func newRawBody(data []byte) *rawBody {
+	b := &rawBody{data: data}
+	b.Reset(data)
+	return b
+}
+
+func (b *rawBody) Close() error { return nil }
+
+// rawContentType is the Content-Type header of raw requests, shared by
+// them rather than allocated by Header.Add for each one. It isn't modified.
+var rawContentType = []string{"text/json"}
+
+// newRawRequest returns a POST request of reqBytes to url with ctx, with a
+// rawBody.
+func newRawRequest(ctx context.Context, url string, reqBytes []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	body := newRawBody(reqBytes)
+	req.Body = body
+	req.ContentLength = int64(len(reqBytes))
+	req.GetBody = body.getBody
+	req.Header["Content-Type"] = rawContentType
+	return req, nil
+}
+
+// getBody returns a new body of the same bytes, for the request to be sent
+// again.
+func (b *rawBody) getBody() (io.ReadCloser, error) {
+	return newRawBody(b.data), nil
+}
@@ -0,0 +1,283 @@
+package loomclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jsimnz/loombench/types"
+	"github.com/loomnetwork/go-loom"
+	"github.com/loomnetwork/go-loom/auth"
+	"github.com/mailru/easyjson/jwriter"
+	"golang.org/x/crypto/ed25519"
+)
+
+// The benchmarks compare the allocations of the raw request path with
+// encoding/json ("std") and with the easyjson marshalers and pooled buffers
+// ("fast"). Run them with
+//
+//	go test -run XXX -bench . -benchmem ./loomclient
+
+var benchModes = []struct {
+	name string
+	fast bool
+}{
+	{"std", false},
+	{"fast", true},
+}
+
+const benchResponse = `{"jsonrpc":"2.0","id":"1","result":{"check_tx":{"code":0,"log":""},"deliver_tx":{"code":0,"log":"","data":"CgVoZWxsbxIFd29ybGQ="},"hash":"2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF","height":12345}}`
+
+// staticTransport replies to every request with body, so that the
+// benchmarks measure the client rather than the network.
+type staticTransport []byte
+
+func (t staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(t)),
+		Request:    req,
+	}, nil
+}
+
+func newBenchContract(b *testing.B, fast bool) (*Contract, auth.Signer, []byte) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	signer := auth.NewEd25519Signer(priv)
+	httpclient := &http.Client{Transport: staticTransport(benchResponse)}
+	client := NewDAppChainRPCClient(httpclient, "default", "http://localhost:46658/rpc", "http://localhost:46658/query")
+	client.UseFastJSON(fast)
+	contract := NewContract(client, loom.LocalAddressFromPublicKey(pub))
+	txBytes, err := contract.CraftCallTx("Set", &types.LoomBenchWriteTx{Key: []byte("hello"), Val: []byte("world")}, signer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return contract, signer, txBytes
+}
+
+// BenchmarkCraftRawRequest measures the encoding of a raw request: the
+// params of the signed tx and the JSON-RPC request around them.
+func BenchmarkCraftRawRequest(b *testing.B) {
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			contract, signer, txBytes := newBenchContract(b, mode.fast)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				params, err := contract.SignTxBytes(txBytes, uint64(i), signer)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := contract.CraftRPCReqBytes("broadcast_tx_commit", params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCallRaw measures sending a crafted raw request and decoding its
+// response.
+func BenchmarkCallRaw(b *testing.B) {
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			contract, signer, txBytes := newBenchContract(b, mode.fast)
+			params, err := contract.SignTxBytes(txBytes, 1, signer)
+			if err != nil {
+				b.Fatal(err)
+			}
+			reqBytes, err := contract.CraftRPCReqBytes("broadcast_tx_commit", params)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := contract.CallRaw(ctx, reqBytes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRawRequest measures the whole raw request path of a request of
+// the load test, from signing to decoding the response.
+func BenchmarkRawRequest(b *testing.B) {
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			contract, signer, txBytes := newBenchContract(b, mode.fast)
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				params, err := contract.SignTxBytes(txBytes, uint64(i), signer)
+				if err != nil {
+					b.Fatal(err)
+				}
+				reqBytes, err := contract.CraftRPCReqBytes("broadcast_tx_commit", params)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := contract.CallRaw(ctx, reqBytes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestFastJSON checks that both modes craft the same request and decode
+// the same result.
+func TestFastJSON(t *testing.T) {
+	var reqs [2][]byte
+	var commits [2]CommitInfo
+	for i, fast := range []bool{false, true} {
+		pub, priv, err := ed25519.GenerateKey(bytes.NewReader(make([]byte, 64)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := auth.NewEd25519Signer(priv)
+		client := NewDAppChainRPCClient(&http.Client{Transport: staticTransport(benchResponse)}, "default", "http://localhost:46658/rpc", "http://localhost:46658/query")
+		client.UseFastJSON(fast)
+		contract := NewContract(client, loom.LocalAddressFromPublicKey(pub))
+		params, err := contract.SignTxBytes([]byte("tx"), 7, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reqs[i], err = contract.CraftRPCReqBytes("broadcast_tx_commit", params); err != nil {
+			t.Fatal(err)
+		}
+		if err := contract.CallRaw(context.Background(), reqs[i]); err != nil {
+			t.Fatal(err)
+		}
+		commits[i] = client.LastCommit()
+	}
+	if !bytes.Equal(reqs[0], reqs[1]) {
+		t.Errorf("requests differ:\n%s\n%s", reqs[0], reqs[1])
+	}
+	if commits[0] != commits[1] || commits[1].Height != 12345 {
+		t.Errorf("commits differ: %+v, %+v", commits[0], commits[1])
+	}
+}
+
+// sizedValue encodes as a JSON string of n bytes, and fails with err after
+// writing it if err is set.
+type sizedValue struct {
+	n   int
+	err error
+}
+
+func (v sizedValue) MarshalEasyJSON(w *jwriter.Writer) {
+	w.String(strings.Repeat("x", v.n))
+	if v.err != nil {
+		w.Error = v.err
+	}
+}
+
+// TestMarshalFast checks encodings that fit the pooled buffer and ones
+// that outgrow it, and that the error of either is returned.
+func TestMarshalFast(t *testing.T) {
+	fail := errors.New("unsupported value")
+	for _, n := range []int{10, minPooledBuffer * 4} {
+		got, err := marshalFast(sizedValue{n: n})
+		if err != nil {
+			t.Errorf("%d bytes: %v", n, err)
+		} else if want := `"` + strings.Repeat("x", n) + `"`; string(got) != want {
+			t.Errorf("%d bytes: got %d bytes, want %d", n, len(got), len(want))
+		}
+		if got, err := marshalFast(sizedValue{n: n, err: fail}); err != fail || got != nil {
+			t.Errorf("%d bytes: marshalFast() = %q, %v, want nil, %v", n, got, err, fail)
+		}
+	}
+}
+
+// errorKind describes err by its type, and the part of the response it
+// failed to decode.
+func errorKind(err error) string {
+	switch err := err.(type) {
+	case nil:
+		return "ok"
+	case *ResponseError:
+		return "response error " + err.RPCError.Message
+	case *DecodeError:
+		return "decode " + err.What
+	default:
+		return err.Error()
+	}
+}
+
+// TestDecodeRawFast checks that the one pass decoding of raw responses
+// fails like encoding/json on the responses it can't decode.
+func TestDecodeRawFast(t *testing.T) {
+	tests := []struct {
+		name string
+		resp string
+		want string
+	}{
+		{"committed", benchResponse, "ok"},
+		{"extra fields", `{"jsonrpc":"2.0","id":"1","x":[1,{"y":null}],"result":{"hash":"AB","height":3,"z":{}}}`, "ok"},
+		{"null result", `{"jsonrpc":"2.0","id":"1","result":null}`, "ok"},
+		{"rpc error", `{"jsonrpc":"2.0","id":"1","error":{"code":-32603,"message":"tx already exists"}}`, "response error tx already exists"},
+		{"null error", `{"jsonrpc":"2.0","id":"1","result":{"height":3},"error":null}`, "ok"},
+		{"no result", `{"jsonrpc":"2.0","id":"1"}`, "decode rpc response result"},
+		{"bad result", `{"jsonrpc":"2.0","id":"1","result":"v"}`, "decode rpc response result"},
+		{"bad height", `{"jsonrpc":"2.0","id":"1","result":{"height":"3"}}`, "decode rpc response result"},
+		{"not json", "Bad Gateway", "decode rpc response"},
+		{"truncated", `{"jsonrpc":"2.0","id":"1"`, "decode rpc response"},
+	}
+	for _, tt := range tests {
+		var got [2]string
+		var results [2]BroadcastTxCommitResult
+		for i, fast := range []bool{false, true} {
+			c := NewJSONRPCClient(&http.Client{Transport: staticTransport(tt.resp)}, "http://localhost:46658/rpc")
+			c.fastJSON = fast
+			got[i] = errorKind(c.CallRaw(context.Background(), []byte(`{}`), &results[i]))
+		}
+		if got[1] != tt.want || got[0] != tt.want {
+			t.Errorf("%s: std %s, fast %s, want %s", tt.name, got[0], got[1], tt.want)
+		}
+		if tt.want == "ok" && !reflect.DeepEqual(results[0], results[1]) {
+			t.Errorf("%s: results differ: %+v, %+v", tt.name, results[0], results[1])
+		}
+	}
+}
+
+// TestRawRequest checks the request of the fast path: its body can be read
+// again through GetBody, for the request to be retried.
+func TestRawRequest(t *testing.T) {
+	reqBytes := []byte(`{"jsonrpc":"2.0","method":"broadcast_tx_commit"}`)
+	req, err := newRawRequest(context.Background(), "http://localhost:46658/rpc", reqBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ContentLength != int64(len(reqBytes)) || req.Header.Get("Content-Type") != "text/json" {
+		t.Errorf("content length %d, type %q, want %d, text/json", req.ContentLength, req.Header.Get("Content-Type"), len(reqBytes))
+	}
+	for i := 0; i < 2; i++ {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil || !bytes.Equal(body, reqBytes) {
+			t.Errorf("body %d = %q, %v, want %q", i, body, err, reqBytes)
+		}
+		req.Body.Close()
+		if req.Body, err = req.GetBody(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package loomclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of a HealthChecker.
+const (
+	defaultHealthInterval = time.Second
+	defaultHealthFailures = 2
+)
+
+// healthKey is the public key whose nonce is queried to check read
+// endpoints. It belongs to no account, so its nonce is always 0.
+var healthKey = hex.EncodeToString(make([]byte, 32))
+
+// HealthTarget is a DAppChain node checked by a HealthChecker.
+type HealthTarget struct {
+	// Name identifies the node.
+	Name string
+
+	// WriteURI and ReadURI are the endpoints of the node txs and queries
+	// are sent to. An empty one isn't checked.
+	WriteURI string
+	ReadURI  string
+}
+
+// HealthChecker checks the health of DAppChain nodes in the background, by
+// calling the status RPC of their write endpoint and querying a nonce from
+// their read endpoint. A node is healthy until it fails Failures checks in a
+// row, on either endpoint, and healthy again once it passes one.
+type HealthChecker struct {
+	// Interval is the time between the checks of a node. Default is 1s.
+	Interval time.Duration
+
+	// Failures is the number of failed checks in a row after which a node
+	// is unhealthy. Default is 2.
+	Failures int
+
+	// OnChange, if non-nil, is called with the name of a node when it
+	// becomes unhealthy, with the error of its last check, or healthy
+	// again. Calls are made one at a time.
+	OnChange func(name string, healthy bool, err error)
+
+	targets []healthTarget
+
+	mu     sync.RWMutex
+	health map[string]*nodeHealth
+}
+
+type healthTarget struct {
+	name        string
+	write, read *JSONRPCClient
+}
+
+type nodeHealth struct {
+	healthy  bool
+	failures int
+}
+
+// NewHealthChecker returns a checker of targets that calls them with
+// client. All the targets are healthy until checked.
+func NewHealthChecker(client *http.Client, targets ...HealthTarget) *HealthChecker {
+	h := &HealthChecker{
+		health: make(map[string]*nodeHealth, len(targets)),
+	}
+	for _, t := range targets {
+		ht := healthTarget{name: t.Name}
+		if t.WriteURI != "" {
+			ht.write = NewJSONRPCClient(client, t.WriteURI)
+		}
+		if t.ReadURI != "" {
+			ht.read = NewJSONRPCClient(client, t.ReadURI)
+		}
+		h.targets = append(h.targets, ht)
+		h.health[t.Name] = &nodeHealth{healthy: true}
+	}
+	return h
+}
+
+// Healthy reports whether the node called name is healthy. Nodes that
+// aren't checked are always healthy.
+func (h *HealthChecker) Healthy(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n, ok := h.health[name]
+	return !ok || n.healthy
+}
+
+// Run checks the nodes every Interval until ctx is done.
+func (h *HealthChecker) Run(ctx context.Context) {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.Check(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check checks both endpoints of every node once, concurrently.
+func (h *HealthChecker) Check(ctx context.Context) {
+	writeErrs := make([]error, len(h.targets))
+	readErrs := make([]error, len(h.targets))
+	var wg sync.WaitGroup
+	for i, t := range h.targets {
+		if t.write != nil {
+			wg.Add(1)
+			go func(i int, c *JSONRPCClient) {
+				defer wg.Done()
+				var status json.RawMessage
+				writeErrs[i] = c.Call(ctx, "status", map[string]interface{}{}, "health", &status)
+			}(i, t.write)
+		}
+		if t.read != nil {
+			wg.Add(1)
+			go func(i int, c *JSONRPCClient) {
+				defer wg.Done()
+				var nonce json.RawMessage
+				readErrs[i] = c.Call(ctx, "nonce", map[string]interface{}{"key": healthKey}, "health", &nonce)
+			}(i, t.read)
+		}
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// The checks were aborted, they say nothing about the nodes.
+		return
+	}
+	for i, t := range h.targets {
+		err := writeErrs[i]
+		if err != nil {
+			err = fmt.Errorf("write endpoint: %v", err)
+		} else if readErrs[i] != nil {
+			err = fmt.Errorf("read endpoint: %v", readErrs[i])
+		}
+		h.record(t.name, err)
+	}
+}
+
+// record updates the health of the node called name with the error of its
+// last check.
+func (h *HealthChecker) record(name string, err error) {
+	failures := h.Failures
+	if failures <= 0 {
+		failures = defaultHealthFailures
+	}
+	h.mu.Lock()
+	n := h.health[name]
+	wasHealthy := n.healthy
+	if err == nil {
+		n.failures = 0
+		n.healthy = true
+	} else {
+		n.failures++
+		if n.failures >= failures {
+			n.healthy = false
+		}
+	}
+	changed := n.healthy != wasHealthy
+	healthy := n.healthy
+	h.mu.Unlock()
+	if changed && h.OnChange != nil {
+		h.OnChange(name, healthy, err)
+	}
+}
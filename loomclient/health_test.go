@@ -0,0 +1,184 @@
+package loomclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type healthChange struct {
+	name    string
+	healthy bool
+}
+
+func TestHealthCheckerRecord(t *testing.T) {
+	fail := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		failures int
+		checks   []error
+		healthy  []bool
+		changes  []healthChange
+	}{
+		{
+			name:    "healthy",
+			checks:  []error{nil, nil, nil},
+			healthy: []bool{true, true, true},
+		},
+		{
+			name:    "one failure",
+			checks:  []error{fail, nil, fail, nil},
+			healthy: []bool{true, true, true, true},
+		},
+		{
+			name:    "default threshold",
+			checks:  []error{fail, fail, fail, nil},
+			healthy: []bool{true, false, false, true},
+			changes: []healthChange{{"a", false}, {"a", true}},
+		},
+		{
+			name:     "custom threshold",
+			failures: 3,
+			checks:   []error{fail, fail, nil, fail, fail, fail},
+			healthy:  []bool{true, true, true, true, true, false},
+			changes:  []healthChange{{"a", false}},
+		},
+		{
+			name:     "every failure",
+			failures: 1,
+			checks:   []error{fail, nil, fail},
+			healthy:  []bool{false, true, false},
+			changes:  []healthChange{{"a", false}, {"a", true}, {"a", false}},
+		},
+	}
+	for _, tt := range tests {
+		h := NewHealthChecker(http.DefaultClient, HealthTarget{Name: "a"}, HealthTarget{Name: "b"})
+		h.Failures = tt.failures
+		var changes []healthChange
+		h.OnChange = func(name string, healthy bool, err error) {
+			if !healthy && err != fail {
+				t.Errorf("%s: ejected with error %v, want %v", tt.name, err, fail)
+			}
+			changes = append(changes, healthChange{name, healthy})
+		}
+		for i, err := range tt.checks {
+			h.record("a", err)
+			h.record("b", nil)
+			if got := h.Healthy("a"); got != tt.healthy[i] {
+				t.Errorf("%s: after check %d healthy = %v, want %v", tt.name, i, got, tt.healthy[i])
+			}
+			if !h.Healthy("b") {
+				t.Errorf("%s: after check %d the other node is unhealthy", tt.name, i)
+			}
+		}
+		if len(changes) != len(tt.changes) {
+			t.Errorf("%s: changes = %v, want %v", tt.name, changes, tt.changes)
+			continue
+		}
+		for i := range changes {
+			if changes[i] != tt.changes[i] {
+				t.Errorf("%s: changes = %v, want %v", tt.name, changes, tt.changes)
+				break
+			}
+		}
+	}
+
+	h := NewHealthChecker(http.DefaultClient)
+	if !h.Healthy("unknown") {
+		t.Error("unchecked node is unhealthy")
+	}
+}
+
+// healthServer serves the JSON-RPC method of a health check, failing while
+// down is set, and counts the calls to it.
+func healthServer(t *testing.T, method string, down *int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"method":"`+method+`"`) {
+			t.Errorf("got request %s, want method %s", body, method)
+		}
+		if atomic.LoadInt32(down) != 0 {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":"health","error":{"code":-32603,"message":"Internal error"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"health","result":{}}`))
+	}))
+}
+
+func TestHealthCheckerCheck(t *testing.T) {
+	var writeDown, readDown, writeCalls, readCalls int32
+	write := healthServer(t, "status", &writeDown, &writeCalls)
+	defer write.Close()
+	read := healthServer(t, "nonce", &readDown, &readCalls)
+	defer read.Close()
+
+	// Both nodes share the write endpoint, only the first uses the read
+	// endpoint under test.
+	var otherCalls, otherDown int32
+	other := healthServer(t, "nonce", &otherDown, &otherCalls)
+	defer other.Close()
+	h := NewHealthChecker(http.DefaultClient,
+		HealthTarget{Name: "a", WriteURI: write.URL, ReadURI: read.URL},
+		HealthTarget{Name: "b", WriteURI: write.URL, ReadURI: other.URL},
+	)
+	var lastErr error
+	h.OnChange = func(name string, healthy bool, err error) {
+		lastErr = err
+	}
+	ctx := context.Background()
+
+	h.Check(ctx)
+	if !h.Healthy("a") || !h.Healthy("b") {
+		t.Fatal("nodes are unhealthy with both endpoints up")
+	}
+	if writeCalls != 2 || readCalls != 1 || otherCalls != 1 {
+		t.Errorf("calls = %d write, %d read, %d other, want 2, 1, 1", writeCalls, readCalls, otherCalls)
+	}
+
+	// A node whose read endpoint is down is ejected, even though its write
+	// endpoint is up.
+	atomic.StoreInt32(&readDown, 1)
+	h.Check(ctx)
+	h.Check(ctx)
+	if h.Healthy("a") {
+		t.Error("node with its read endpoint down is healthy")
+	}
+	if !h.Healthy("b") {
+		t.Error("node b is unhealthy, only node a's read endpoint is down")
+	}
+	if lastErr == nil || !strings.HasPrefix(lastErr.Error(), "read endpoint: ") {
+		t.Errorf("ejection error = %v, want a read endpoint error", lastErr)
+	}
+
+	atomic.StoreInt32(&readDown, 0)
+	h.Check(ctx)
+	if !h.Healthy("a") {
+		t.Error("node a wasn't readmitted")
+	}
+
+	// Both nodes go down with the write endpoint they share.
+	atomic.StoreInt32(&writeDown, 1)
+	h.Check(ctx)
+	h.Check(ctx)
+	if h.Healthy("a") || h.Healthy("b") {
+		t.Error("nodes with their write endpoint down are healthy")
+	}
+	if lastErr == nil || !strings.HasPrefix(lastErr.Error(), "write endpoint: ") {
+		t.Errorf("ejection error = %v, want a write endpoint error", lastErr)
+	}
+
+	// Aborted checks don't count.
+	atomic.StoreInt32(&writeDown, 0)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	h.Check(cancelled)
+	if h.Healthy("a") || h.Healthy("b") {
+		t.Error("aborted check readmitted the nodes")
+	}
+}
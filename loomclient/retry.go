@@ -0,0 +1,107 @@
+package loomclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default wait before the first retry of a RetryPolicy.
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// ErrCategories are the categories ErrorCategory returns for errors.
+var ErrCategories = []string{
+	ErrCategoryTimeout,
+	ErrCategoryTransport,
+	ErrCategoryRPC,
+	ErrCategoryDecode,
+	ErrCategoryNonce,
+	ErrCategoryCheckTx,
+	ErrCategoryDeliverTx,
+	ErrCategoryOther,
+}
+
+// RetryPolicy retries calls that fail with retryable errors.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is made, including the
+	// first. A policy with a MaxAttempts of 1 or less never retries.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is the wait before the first retry, doubled before each
+	// following one up to MaxBackoff. Default is 100ms.
+	Backoff    time.Duration `json:"backoff"`
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+
+	// Categories are the categories of errors, as returned by
+	// ErrorCategory or the classifier given to Do, that are retried.
+	// Default is ErrCategoryTransport and ErrCategoryTimeout, the errors of
+	// calls that may not have reached the node.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// ParseRetryCategories returns the categories of a comma separated list, as
+// given to RetryPolicy.Categories.
+func ParseRetryCategories(s string) ([]string, error) {
+	var categories []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		known := false
+		for _, k := range ErrCategories {
+			known = known || c == k
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown error category %q", c)
+		}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+// Retryable reports whether a call that failed with an error of category
+// is retried.
+func (p *RetryPolicy) Retryable(category string) bool {
+	if category == "" || category == ErrCategoryCancelled {
+		return false
+	}
+	if len(p.Categories) == 0 {
+		return category == ErrCategoryTransport || category == ErrCategoryTimeout
+	}
+	for _, c := range p.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Do calls call until it succeeds, fails with an error that isn't
+// retryable, or was made MaxAttempts times, waiting between the attempts.
+// The errors are categorized by classify, or by ErrorCategory if it is nil.
+// It returns the number of attempts made and the error of the last one.
+func (p *RetryPolicy) Do(ctx context.Context, call func() error, classify func(error) string) (int, error) {
+	if classify == nil {
+		classify = ErrorCategory
+	}
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(classify(err)) {
+			return attempt, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, err
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package loomclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// failingCall returns a call that fails with err its first failures times
+// and then succeeds, recording the time of every attempt.
+func failingCall(failures int, err error, times *[]time.Time) func() error {
+	return func() error {
+		*times = append(*times, time.Now())
+		if len(*times) <= failures {
+			return err
+		}
+		return nil
+	}
+}
+
+// errRefused is a transport error, retried by default.
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := []struct {
+		categories []string
+		category   string
+		want       bool
+	}{
+		{nil, ErrCategoryTransport, true},
+		{nil, ErrCategoryTimeout, true},
+		{nil, ErrCategoryCheckTx, false},
+		{nil, "stale", false},
+		{nil, ErrCategoryCancelled, false},
+		{nil, "", false},
+		{[]string{ErrCategoryNonce}, ErrCategoryNonce, true},
+		{[]string{ErrCategoryNonce}, ErrCategoryTransport, false},
+		{[]string{"stale", ErrCategoryTimeout}, "stale", true},
+		{[]string{ErrCategoryCancelled}, ErrCategoryCancelled, false},
+	}
+	for _, tt := range tests {
+		p := &RetryPolicy{Categories: tt.categories}
+		if got := p.Retryable(tt.category); got != tt.want {
+			t.Errorf("%v: Retryable(%q) = %v, want %v", tt.categories, tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		failures    int
+		categories  []string
+		classify    func(error) string
+		attempts    int
+		fail        bool
+	}{
+		{name: "success", maxAttempts: 3, attempts: 1},
+		{name: "no retries", maxAttempts: 1, failures: 2, attempts: 1, fail: true},
+		{name: "recovers", maxAttempts: 3, failures: 2, attempts: 3},
+		{name: "exhausted", maxAttempts: 3, failures: 5, attempts: 3, fail: true},
+		{
+			name:        "not retryable",
+			maxAttempts: 3,
+			failures:    2,
+			categories:  []string{ErrCategoryNonce},
+			attempts:    1,
+			fail:        true,
+		},
+		{
+			name:        "classified",
+			maxAttempts: 5,
+			failures:    3,
+			categories:  []string{"stale"},
+			classify:    func(error) string { return "stale" },
+			attempts:    4,
+		},
+		{
+			name:        "classified not retryable",
+			maxAttempts: 5,
+			failures:    3,
+			classify:    func(error) string { return "stale" },
+			attempts:    1,
+			fail:        true,
+		},
+	}
+	for _, tt := range tests {
+		p := &RetryPolicy{MaxAttempts: tt.maxAttempts, Backoff: time.Millisecond, Categories: tt.categories}
+		var times []time.Time
+		attempts, err := p.Do(context.Background(), failingCall(tt.failures, errRefused, &times), tt.classify)
+		if attempts != tt.attempts || len(times) != tt.attempts {
+			t.Errorf("%s: %d attempts, %d calls, want %d", tt.name, attempts, len(times), tt.attempts)
+		}
+		if (err != nil) != tt.fail {
+			t.Errorf("%s: error = %v, want failure %v", tt.name, err, tt.fail)
+		}
+	}
+}
+
+func TestRetryPolicyDoBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		MaxAttempts: 6,
+		Backoff:     10 * time.Millisecond,
+		MaxBackoff:  40 * time.Millisecond,
+	}
+	var times []time.Time
+	attempts, err := p.Do(context.Background(), failingCall(5, errRefused, &times), nil)
+	if err != nil || attempts != 6 {
+		t.Fatalf("Do() = %d, %v, want 6 attempts and no error", attempts, err)
+	}
+	// The backoff doubles up to MaxBackoff.
+	want := []time.Duration{10, 20, 40, 40, 40}
+	for i := 1; i < len(times); i++ {
+		if wait := times[i].Sub(times[i-1]); wait < want[i-1]*time.Millisecond {
+			t.Errorf("wait before attempt %d = %v, want at least %v", i+1, wait, want[i-1]*time.Millisecond)
+		}
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	var times []time.Time
+	call := failingCall(5, errRefused, &times)
+	attempts, err := p.Do(ctx, func() error {
+		defer cancel()
+		return call()
+	}, nil)
+	if attempts != 1 || err == nil {
+		t.Errorf("Do() = %d, %v, want 1 attempt and its error", attempts, err)
+	}
+}
@@ -2,13 +2,13 @@ package loomclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptrace"
-	"net/url"
 )
 
 //easyjson:json
@@ -53,50 +53,55 @@ func NewRPCRequest(method string, params json.RawMessage, id string) RPCRequest
 type JSONRPCClient struct {
 	host     string
 	client   *http.Client
-	reqMaker func(*http.Request) *http.Request
+	endpoint string
+	tracer   Tracer
+
+	// fastJSON decodes the responses of CallRaw with the easyjson
+	// unmarshalers and pooled buffers.
+	fastJSON bool
 }
 
 type TracedJSONRPCClient struct {
 	JSONRPCClient
 }
 
-func newHTTPDialer(host string) func(string, string) (net.Conn, error) {
-	u, err := url.Parse(host)
-	// default to tcp if nothing specified
-	protocol := u.Scheme
-	if err != nil {
-		return func(_ string, _ string) (net.Conn, error) {
+// newHTTPDialer returns a dialer for host. It dials with the request's
+// context, so DNS lookup and connect events reach its httptrace.ClientTrace.
+// The dialer may be shared by clients of other hosts through their
+// transport, so it dials the address requests are sent to, see requestURL.
+func newHTTPDialer(host string) func(context.Context, string, string) (net.Conn, error) {
+	if _, err := requestURL(host); err != nil {
+		return func(_ context.Context, _ string, _ string) (net.Conn, error) {
 			return nil, fmt.Errorf("Invalid host: %s", host)
 		}
 	}
-	if protocol == "http" {
-		protocol = "tcp"
-	}
-	return func(p, a string) (net.Conn, error) {
-		return net.Dial(protocol, u.Host)
-	}
+	return DialEndpoint
 }
 
 func DefaultHTTPClient(host string) *http.Client {
 	return &http.Client{
 		Transport: &http.Transport{
-			Dial: newHTTPDialer(host),
+			DialContext: newHTTPDialer(host),
 		},
 	}
 }
 
 func NewJSONRPCClient(client *http.Client, host string) *JSONRPCClient {
+	// Transports that dial on their own, or aren't an *http.Transport such
+	// as HTTP/2 ones, are used as is.
 	if client.Transport == nil {
 		tr := &http.Transport{
-			Dial: newHTTPDialer(host),
+			DialContext: newHTTPDialer(host),
 		}
 		client.Transport = tr
-	} else if tr := client.Transport.(*http.Transport); tr.Dial == nil {
-		// tr := client.Transport.(*http.Transport)
-		tr.Dial = newHTTPDialer(host)
-		client.Transport = tr
+	} else if tr, ok := client.Transport.(*http.Transport); ok && tr.Dial == nil && tr.DialContext == nil {
+		tr.DialContext = newHTTPDialer(host)
 	}
 
+	// Invalid hosts are kept as is, for requests to fail with their error.
+	if u, err := requestURL(host); err == nil {
+		host = u
+	}
 	return &JSONRPCClient{
 		host:   host,
 		client: client,
@@ -108,19 +113,42 @@ func NewJSONRPCClient(client *http.Client, host string) *JSONRPCClient {
 // 		host: host,
 // 		client: &http.Client{
 // 			Transport: &http.Transport{
-// 				Dial: newHTTPDialer(host),
+// 				DialContext: newHTTPDialer(host),
 // 			},
 // 		},
 // 	}
 // }
 
+// UseTrace installs trace on every call made by the client.
+//
+// Deprecated: the trace is shared by all calls, use UseTracer to trace each
+// call separately.
 func (c *JSONRPCClient) UseTrace(trace *httptrace.ClientTrace) {
-	c.reqMaker = func(req *http.Request) *http.Request {
-		return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	c.UseTracer(c.endpoint, func(string, string) *CallTrace {
+		return &CallTrace{ClientTrace: trace}
+	})
+}
+
+// UseTracer installs tracer on the client, which is passed endpoint as the
+// name of the endpoint of each call.
+func (c *JSONRPCClient) UseTracer(endpoint string, tracer Tracer) {
+	c.endpoint = endpoint
+	c.tracer = tracer
+}
+
+func (c *JSONRPCClient) startTrace(method string) *CallTrace {
+	if c.tracer == nil {
+		return nil
 	}
+	return c.tracer(c.endpoint, method)
 }
 
-func (c *JSONRPCClient) Call(method string, params map[string]interface{}, id string, result interface{}) error {
+func (c *JSONRPCClient) Call(ctx context.Context, method string, params map[string]interface{}, id string, result interface{}) (err error) {
+	trace := c.startTrace(method)
+	defer func() {
+		trace.done(err)
+	}()
+
 	paramsBytes, err := json.Marshal(params)
 	if err != nil {
 		return err
@@ -131,12 +159,20 @@ func (c *JSONRPCClient) Call(method string, params map[string]interface{}, id st
 		return err
 	}
 
-	req, err := http.NewRequest("POST", c.host, bytes.NewBuffer(reqBytes))
+	var req *http.Request
+	if c.fastJSON {
+		req, err = newRawRequest(ctx, c.host, reqBytes)
+	} else {
+		req, err = http.NewRequest("POST", c.host, bytes.NewBuffer(reqBytes))
+		if err == nil {
+			req.Header.Add("Content-Type", "text/json")
+			req = req.WithContext(ctx)
+		}
+	}
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "text/json")
-	resp, err := c.doReq(req)
+	resp, err := c.doReq(trace.withTrace(req))
 	// resp, err := c.client.Post(c.host, "text/json", )
 
 	if err != nil {
@@ -149,32 +185,48 @@ func (c *JSONRPCClient) Call(method string, params map[string]interface{}, id st
 	}
 	var rpcResp RPCResponse
 	if err := json.Unmarshal(respBytes, &rpcResp); err != nil {
-		return fmt.Errorf("error unmarshalling rpc response: %v", err)
+		return &DecodeError{What: "rpc response", Err: err}
 	}
 	if rpcResp.Error != nil {
-		return fmt.Errorf("Response error: %v", rpcResp.Error)
+		return &ResponseError{RPCError: rpcResp.Error}
 	}
 	if result != nil {
 		if err := json.Unmarshal(rpcResp.Result, result); err != nil {
-			return fmt.Errorf("error unmarshalling rpc response result: %v", err)
+			return &DecodeError{What: "rpc response result", Err: err}
 		}
 	}
 	return nil
 }
 
-func (c *JSONRPCClient) CallRaw(reqBytes []byte, result *BroadcastTxCommitResult) error {
-	req, err := http.NewRequest("POST", c.host, bytes.NewBuffer(reqBytes))
+func (c *JSONRPCClient) CallRaw(ctx context.Context, reqBytes []byte, result *BroadcastTxCommitResult) (err error) {
+	trace := c.startTrace("broadcast_tx_commit")
+	defer func() {
+		trace.done(err)
+	}()
+
+	var req *http.Request
+	if c.fastJSON {
+		req, err = newRawRequest(ctx, c.host, reqBytes)
+	} else {
+		req, err = http.NewRequest("POST", c.host, bytes.NewBuffer(reqBytes))
+		if err == nil {
+			req.Header.Add("Content-Type", "text/json")
+			req = req.WithContext(ctx)
+		}
+	}
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "text/json")
-	resp, err := c.doReq(req)
+	resp, err := c.doReq(trace.withTrace(req))
 	// resp, err := c.client.Post(c.host, "text/json", )
 
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if c.fastJSON {
+		return decodeRawFast(resp.Body, result)
+	}
 	respBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
@@ -182,22 +234,19 @@ func (c *JSONRPCClient) CallRaw(reqBytes []byte, result *BroadcastTxCommitResult
 
 	var rpcResp RPCResponse
 	if err := json.Unmarshal(respBytes, &rpcResp); err != nil {
-		return fmt.Errorf("error unmarshalling rpc response: %v", err)
+		return &DecodeError{What: "rpc response", Err: err}
 	}
 	if rpcResp.Error != nil {
-		return fmt.Errorf("Response error: %v", rpcResp.Error)
+		return &ResponseError{RPCError: rpcResp.Error}
 	}
 	if result != nil {
 		if err := json.Unmarshal(rpcResp.Result, result); err != nil {
-			return fmt.Errorf("error unmarshalling rpc response result: %v", err)
+			return &DecodeError{What: "rpc response result", Err: err}
 		}
 	}
 	return nil
 }
 
 func (c *JSONRPCClient) doReq(req *http.Request) (*http.Response, error) {
-	if c.reqMaker != nil {
-		req = c.reqMaker(req)
-	}
 	return c.client.Do(req)
 }
@@ -0,0 +1,80 @@
+package loomclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type NodeInfo struct {
+	ID      string          `json:"id"`
+	Network string          `json:"network"`
+	Version string          `json:"version"`
+	Moniker string          `json:"moniker"`
+	Other   json.RawMessage `json:"other,omitempty"`
+}
+
+type SyncInfo struct {
+	LatestBlockHash   string    `json:"latest_block_hash"`
+	LatestBlockHeight flexInt64 `json:"latest_block_height"`
+	LatestBlockTime   string    `json:"latest_block_time"`
+	CatchingUp        bool      `json:"catching_up"`
+}
+
+// NodeStatus is the result of the Tendermint status RPC.
+type NodeStatus struct {
+	NodeInfo NodeInfo `json:"node_info"`
+	SyncInfo SyncInfo `json:"sync_info"`
+}
+
+// GitCommit returns the source revision the node reports in the "other"
+// node info, or an empty string if it doesn't report one.
+func (s *NodeStatus) GitCommit() string {
+	var other interface{}
+	if len(s.NodeInfo.Other) == 0 || json.Unmarshal(s.NodeInfo.Other, &other) != nil {
+		return ""
+	}
+	isCommitKey := func(k string) bool {
+		k = strings.ToLower(k)
+		return strings.Contains(k, "commit") || strings.Contains(k, "git")
+	}
+	switch other := other.(type) {
+	case []interface{}:
+		// Older nodes report a list of "key=value" strings.
+		for _, kv := range other {
+			str, ok := kv.(string)
+			if !ok {
+				continue
+			}
+			if parts := strings.SplitN(str, "=", 2); len(parts) == 2 && isCommitKey(parts[0]) {
+				return parts[1]
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range other {
+			if str, ok := v.(string); ok && isCommitKey(k) {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+// flexInt64 decodes an integer encoded either as a JSON number or a string,
+// as newer Tendermint versions encode 64-bit integers as strings.
+type flexInt64 int64
+
+func (i *flexInt64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", b)
+	}
+	*i = flexInt64(v)
+	return nil
+}
@@ -0,0 +1,55 @@
+package loomclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSOptions configures the TLS connections to https:// endpoints, such as
+// nodes behind a TLS-terminating proxy with a private CA.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the CAs to verify the certificates of the
+	// endpoints with, instead of the system CAs.
+	CAFile string
+
+	// CertFile and KeyFile are the PEM certificate and key presented to
+	// endpoints that ask for a client certificate. Optional.
+	CertFile string
+	KeyFile  string
+
+	// InsecureSkipVerify accepts any certificate presented by the
+	// endpoints.
+	InsecureSkipVerify bool
+}
+
+// Config returns the tls.Config of the options, or nil if they are all
+// empty.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	if o == (TLSOptions{}) {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" {
+		pem, err := ioutil.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", o.CAFile)
+		}
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,40 @@
+package loomclient
+
+import (
+	"net/http"
+	"net/http/httptrace"
+)
+
+// Endpoints of a DAppChainRPCClient.
+const (
+	EndpointTx    = "tx"    // the write URI txs are submitted to
+	EndpointQuery = "query" // the read URI state is queried from
+)
+
+// CallTrace traces one JSON-RPC call.
+type CallTrace struct {
+	// ClientTrace, if non-nil, receives the HTTP events of the call.
+	ClientTrace *httptrace.ClientTrace
+
+	// Done, if non-nil, is called with the error of the call once it
+	// completes.
+	Done func(err error)
+}
+
+// Tracer returns the trace of a call to method on endpoint, which is
+// EndpointTx or EndpointQuery. It is called before every call, so each call
+// gets its own trace. It may return nil to leave a call untraced.
+type Tracer func(endpoint, method string) *CallTrace
+
+func (t *CallTrace) withTrace(req *http.Request) *http.Request {
+	if t == nil || t.ClientTrace == nil {
+		return req
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), t.ClientTrace))
+}
+
+func (t *CallTrace) done(err error) {
+	if t != nil && t.Done != nil {
+		t.Done(err)
+	}
+}
@@ -16,21 +16,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"strings"
 	// "net/http"
-	// gourl "net/url"
+	gourl "net/url"
 	"os"
 	"os/exec"
 	"os/signal"
 	// "regexp"
 	"runtime"
+	"strconv"
 	// "strings"
 	"time"
 
+	"github.com/jsimnz/loombench/loomclient"
 	"github.com/jsimnz/loombench/requester"
 	"github.com/jsimnz/loombench/types"
 	"github.com/jsimnz/loombench/version"
@@ -55,9 +58,44 @@ var (
 	t = flag.Int("t", 20, "")
 	z = flag.Duration("z", 0, "")
 
-	cpus              = flag.Int("cpus", runtime.GOMAXPROCS(-1), "")
-	disableKeepAlives = flag.Bool("disable-keepalive", false, "")
-
+	warmup  = flag.Duration("warmup", 0, "")
+	warmupN = flag.Int("warmup-n", 0, "")
+
+	live       = flag.Bool("live", false, "")
+	interval   = flag.Duration("interval", time.Second, "")
+	timeSeries = flag.String("timeseries", "", "")
+
+	format        = flag.String("format", "", "")
+	outputFile    = flag.String("output-file", "", "")
+	metricsAddr   = flag.String("metrics-addr", "", "")
+	metricsLinger = flag.Duration("metrics-linger", 0, "")
+	logFile       = flag.String("log-file", "", "")
+	logFormat     = flag.String("log-format", "csv", "")
+
+	cpus               = flag.Int("cpus", runtime.GOMAXPROCS(-1), "")
+	cpuProfile         = flag.String("cpuprofile", "", "")
+	memProfile         = flag.String("memprofile", "", "")
+	disableKeepAlives  = flag.Bool("disable-keepalive", false, "")
+	disableCompression = flag.Bool("disable-compression", false, "")
+	disableRedirects   = flag.Bool("disable-redirects", false, "")
+	h2                 = flag.Bool("h2", false, "")
+	proxyAddr          = flag.String("proxy", "", "")
+	maxIdleConns       = flag.Int("max-idle-conns", 0, "")
+	maxConnsPerHost    = flag.Int("max-conns-per-host", 0, "")
+
+	caCert   = flag.String("cacert", "", "")
+	cert     = flag.String("cert", "", "")
+	certKey  = flag.String("cert-key", "", "")
+	insecure = flag.Bool("insecure", false, "")
+
+	balance        = flag.String("balance", requester.BalanceRoundRobin, "")
+	weights        = flag.String("weights", "", "")
+	healthCheck    = flag.Duration("health-check", 0, "")
+	failover       = flag.Bool("failover", false, "")
+	retries        = flag.Int("retries", 0, "")
+	retryBackoff   = flag.Duration("retry-backoff", 100*time.Millisecond, "")
+	retryOn        = flag.String("retry-on", "transport,timeout", "")
+	batch          = flag.Int("batch", 1, "")
 	writeURL       = flag.String("w", "http://localhost:46658/rpc", "")
 	readURL        = flag.String("r", "http://localhost:46658/query", "")
 	chainID        = flag.String("i", "default", "")
@@ -67,7 +105,22 @@ var (
 	directory      = flag.String("d", "", "")
 	gitPath        = flag.String("g", "$GOPATH/src/github.com/jsimnz/loombench", "")
 
+	verify        = flag.Bool("verify", false, "")
+	verifyURL     = flag.String("verify-url", "", "")
+	verifyTimeout = flag.Duration("verify-timeout", 10*time.Second, "")
+	audit         = flag.Bool("audit", false, "")
+	auditQPS      = flag.Float64("audit-qps", 100, "")
+	auditFile     = flag.String("audit-file", "", "")
+
 	updateGenesis = flag.Bool("update-genesis", false, "")
+	scenarioFile  = flag.String("f", "", "")
+
+	rpsThreshold     = flag.Float64("rps-threshold", 5, "")
+	latencyThreshold = flag.Float64("latency-threshold", 10, "")
+	errorThreshold   = flag.Float64("error-threshold", 0.1, "")
+	alpha            = flag.Float64("alpha", 0.05, "")
+
+	assertions assertSlice
 
 	//optimization
 	rawRequest = flag.Bool("raw-request", false, "")
@@ -75,16 +128,22 @@ var (
 )
 
 var usage = `Usage: loombench [options...] 
+       loombench compare [options...] base.json new.json
 
 Commands:
   install	Add the loombench contract to an existing Loom DAppChain.
   run		Run the benchmarking utility against a running DAppChain.
+  compare	Compare two reports written with -format json. Exits with
+		status 2 if the new run regressed, and 1 if the reports
+		can't be read.
 
 Flags:
   Basic
   =====
   -x  Type of transactions to submit to the DAppChain. 
-      Available values: read, write, mixed.
+      Available values: call, read, write, mixed. Default is call, which
+      calls -m for every request. read, write and mixed Get and Set unique
+      keys of the SimpleStore contract.
   -o  Ratio to use of transaction types between read and write calls.
       Example: -o 0.75 means 75%% of the transactions are reads and
       25%% are writes.
@@ -97,6 +156,43 @@ Flags:
       application stops and exits. If duration is specified, n is ignored.
       Examples: -z 10s -z 3m..
   -t  Timeout for each request in seconds. Default is 20, use 0 for infinite.
+
+  -warmup    Duration at the start of the run whose requests are made but
+             excluded from the summary, histogram and percentiles.
+             Warmup requests count towards -n and -z. Examples: -warmup 10s
+  -warmup-n  Number of requests at the start of the run to treat as warmup.
+
+  -live        Print throughput, error rate and latency percentiles of each
+               interval as a rolling table instead of a progress bar.
+  -interval    Time between live snapshots. Default is 1s.
+  -timeseries  File to write each interval snapshot to as csv.
+
+  -format       Output format of the report. Available values: csv, json, html.
+                Default is a human readable summary. csv streams every request
+                as -log-file would, json writes the full report with run
+                metadata, html writes a single page with charts that can be
+                viewed offline.
+  -output-file  File to write the report to. Default is stdout.
+  -metrics-addr Address to serve Prometheus metrics on during the run,
+                e.g. -metrics-addr :9100. Metrics are served at /metrics.
+                loombench exits if it cannot listen on the address.
+  -metrics-linger
+                How long to keep serving metrics after the run, so that the
+                final values get scraped, e.g. -metrics-linger 30s. Default
+                is 0.
+  -log-file     File to log every request to as it completes, with its send
+                time, op, outcome, phase timings, account, nonce, tx hash and
+                block height. Warmup requests and errors are included.
+  -log-format   Format of -log-file. Available values: csv, ndjson.
+                Default is csv.
+
+  -assert  Condition the run must meet, e.g. -assert "p99 < 2s". Can be
+           repeated. Results are printed as a pass/fail table and loombench
+           exits with status 3 if any fails. Metrics: rps, requests, errors,
+           error_rate, average, fastest, slowest, std_dev and percentiles
+           such as p50 or p99.9. Examples: "error_rate < 0.1%%", "rps > 300".
+           Latency assertions fail if no request succeeded, and error_rate
+           ones if no request was made.
   
   
   Loom
@@ -105,6 +201,28 @@ Flags:
       Default: http://localhost:46658/rpc.
   -r  Read URL for retrieving transactions from a Loom DAppChain 
 	  Default: http://localhost:46658/query.
+      -w and -r take comma separated lists to send requests to several
+      nodes, paired in order. A single URL is shared by all the nodes.
+      URLs take the forms http(s)://<host>:<port>/<path>, tcp://<host>:<port>
+      (plain HTTP) or unix:///<socket>?path=/<path> for a co-located node.
+  -balance  How to distribute requests across nodes: round-robin, random,
+            weighted or sticky (each account always uses the same node).
+            Default is round-robin.
+  -weights  Comma separated weights of the nodes for -balance weighted.
+  -health-check  Interval of the health checks of the write and read URLs
+                 of the nodes, e.g. 1s. Nodes failing two checks in a row
+                 are taken out of rotation until they pass one again.
+                 Default is no health checks.
+  -failover  Retry requests that couldn't reach their node, or timed out,
+             once on another healthy node. Not available with -raw-request.
+  -retries   Number of times a failed request is retried on the same node.
+             Default is 0.
+  -retry-backoff  Wait before the first retry of a request, doubled before
+                  each following one. Default is 100ms.
+  -retry-on  Comma separated categories of errors that are retried: timeout,
+             transport, rpc, decode, nonce, check_tx, deliver_tx or other.
+             Default is transport,timeout. nonce can't be used with
+             -raw-request, whose retries resend the same signed tx.
   -i  Chain ID for the Loom DAppChain. Default: default.
   -a  Address of the contract to execute on the Loom DAppChain. Default: SimpleStore
   -m  Method to invoke when calling the Loom Contract. Default: Set.
@@ -114,18 +232,83 @@ Flags:
   -d  Directory containing a Loom DAppChain instance.
   -g  Path to loombench git source repo. Default: $GOPATH/src/github.com/jsimnz/loombench.
 
+  Verify
+  ======
+  -verify          Read back every successful Set of the write and mixed
+                   workloads until the written value is visible, reporting
+                   lost and mismatched writes and how stale reads were.
+                   Writes are read back in the background, without slowing
+                   down the workers.
+  -verify-url      Read URL to read the writes back from, to check another
+                   node. Default is -r.
+  -verify-timeout  Time a write has to become visible before it counts as
+                   lost. Default is 10s.
+  -audit           Keep every successful Set of the write and mixed workloads
+                   and read them all back once the run is over, reporting
+                   found, missing and mismatched keys and the durability rate.
+                   Reads from -verify-url if given.
+  -audit-qps       Rate of the reads of the audit, in queries per second.
+                   Default is 100.
+  -audit-file      File to write the keys the audit didn't find to as csv,
+                   with the hash and height of their tx.
+
+  TLS
+  ===
+  -w and -r take https:// URLs to connect to nodes over TLS.
+  -cacert    PEM file of the CAs to verify the certificates of the nodes with,
+             instead of the system CAs.
+  -cert      PEM file of the client certificate to present to the nodes.
+  -cert-key  PEM file of the key of the client certificate.
+  -insecure  Accept any certificate presented by the nodes.
+
   Config
   ======
   -disable-keepalive    Disable keep-alive, prevents re-use of TCP
                         connections between different HTTP requests.
+  -disable-compression  Disable compression.
+  -disable-redirects    Disable following of HTTP redirects.
+  -h2                   Enable HTTP/2, negotiated with TLS for https:// nodes
+                        and with prior knowledge (h2c) for the others.
+                        Can't be used with -proxy, -disable-keepalive or
+                        -max-conns-per-host.
+  -proxy                HTTP Proxy address as host:port.
+  -max-idle-conns       Number of idle connections kept open to each node.
+                        Default is -c, up to 500.
+  -max-conns-per-host   Maximum number of connections to each node, including
+                        the ones in use. Default is no limit.
   -cpus                 Number of used cpu cores.
 						(default for current machine is %d cores)
+  -cpuprofile           File to write the pprof CPU profile of loombench
+                        during the run to.
+  -memprofile           File to write the pprof heap profile of loombench
+                        at the end of the run to.
   -update-genesis		Update the genesis.json file when available (loombench install)
+  -f                    Scenario file to read options from. A JSON object mapping
+                        flag names to values, e.g. {"n": 1000, "warmup": "10s"}.
+                        Flags given on the command line take precedence.
+                        Repeatable flags such as assert take an array.
 
   Optimizations
   =============
   -raw-request	Craft a raw marshalled protobuf request ahead of time.
-  -fast-json	Use a faster json encoder, requires -raw-request to be true. 		
+		Cannot be used with -x.
+  -batch	Number of requests of a worker sent together in a JSON-RPC
+		batch. Batched requests are not retried nor failed over.
+		Cannot be used with -raw-request. Default is 1, no batching.
+  -fast-json	Use a faster json encoder, requires -raw-request to be true.
+		Encodes the requests and decodes the responses with the
+		generated easyjson marshalers and pooled buffers.
+
+  Compare
+  =======
+  -rps-threshold      Change in throughput, in percent, beyond which a run
+                      improved or regressed. Default is 5.
+  -latency-threshold  Change in average latency and percentiles, in percent,
+                      beyond which a run improved or regressed. Default is 10.
+  -error-threshold    Change in error rate, in percentage points, beyond which
+                      a run improved or regressed. Default is 0.1.
+  -alpha              Significance level of the t-test on average latencies.
+                      Default is 0.05.
 
   Advanced
   ========
@@ -133,6 +316,7 @@ Flags:
 `
 
 func main() {
+	flag.Var(&assertions, "assert", "")
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, fmt.Sprintf(usage, runtime.NumCPU()))
 	}
@@ -151,6 +335,8 @@ func main() {
 		runCmd()
 	} else if cmd == "install" {
 		installCmd()
+	} else if cmd == "compare" {
+		compareCmd()
 	} else if cmd == "help" {
 		usageAndExit("")
 	} else {
@@ -217,6 +403,12 @@ func installCmd() {
 }
 
 func runCmd() {
+	if *scenarioFile != "" {
+		if err := loadScenario(*scenarioFile); err != nil {
+			errAndExit(err.Error())
+		}
+	}
+
 	runtime.GOMAXPROCS(*cpus)
 	num := *n
 	conc := *c
@@ -228,6 +420,9 @@ func runCmd() {
 		if conc <= 0 {
 			usageAndExit("-c cannot be smaller than 1.")
 		}
+		if *warmup >= dur {
+			usageAndExit("-warmup must be shorter than -z.")
+		}
 	} else {
 		if num <= 0 || conc <= 0 {
 			usageAndExit("-n and -c cannot be smaller than 1.")
@@ -236,6 +431,46 @@ func runCmd() {
 		if num < conc {
 			usageAndExit("-n cannot be less than -c.")
 		}
+
+		if *warmupN >= num {
+			usageAndExit("-warmup-n must be less than -n.")
+		}
+	}
+
+	if *warmup < 0 || *warmupN < 0 {
+		usageAndExit("-warmup and -warmup-n cannot be negative.")
+	}
+
+	if *metricsLinger < 0 {
+		usageAndExit("-metrics-linger cannot be negative.")
+	}
+
+	if *interval <= 0 {
+		usageAndExit("-interval must be greater than 0.")
+	}
+
+	switch *format {
+	case "", "csv", "json", "html":
+	default:
+		usageAndExit(fmt.Sprintf("%s is not a valid -format.", *format))
+	}
+	if *format == "csv" && *logFile != "" {
+		usageAndExit("-format csv streams the request log to -output-file, it cannot be used with -log-file.")
+	}
+
+	switch *logFormat {
+	case requester.ResultLogCSV, requester.ResultLogNDJSON:
+	default:
+		usageAndExit(fmt.Sprintf("%s is not a valid -log-format.", *logFormat))
+	}
+
+	var asserts []*requester.Assertion
+	for _, expr := range assertions {
+		a, err := requester.ParseAssertion(expr)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		asserts = append(asserts, a)
 	}
 
 	// Craft transaction body
@@ -248,24 +483,232 @@ func runCmd() {
 		usageAndExit("Fast JSON optimization requires the -raw-request flag")
 	}
 
+	if *batch < 1 {
+		usageAndExit("-batch must be at least 1.")
+	}
+	if *batch > 1 && *rawRequest {
+		usageAndExit("-batch cannot be used with -raw-request.")
+	}
+	if *failover && *rawRequest {
+		usageAndExit("-failover cannot be used with -raw-request.")
+	}
+
+	if *transactions != "" {
+		if *rawRequest {
+			usageAndExit("-x cannot be used with -raw-request.")
+		}
+		if !isWorkload(*transactions) {
+			usageAndExit(fmt.Sprintf("%s is not a valid -x, available values: %s.", *transactions, strings.Join(requester.Workloads(), ", ")))
+		}
+	}
+
+	var nodeWeights []float64
+	for _, s := range strings.Split(*weights, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			usageAndExit(fmt.Sprintf("%s is not a valid -weights value.", s))
+		}
+		nodeWeights = append(nodeWeights, v)
+	}
+	nodes, err := requester.ParseNodes(*writeURL, *readURL, nodeWeights)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+
+	switch *balance {
+	case requester.BalanceRoundRobin, requester.BalanceRandom, requester.BalanceWeighted, requester.BalanceSticky:
+	default:
+		usageAndExit(fmt.Sprintf("%s is not a valid -balance.", *balance))
+	}
+	if len(nodeWeights) > 0 && *balance != requester.BalanceWeighted {
+		usageAndExit("-weights can only be used with -balance weighted.")
+	}
+
+	if *healthCheck < 0 {
+		usageAndExit("-health-check must not be negative.")
+	}
+
+	if *retries < 0 {
+		usageAndExit("-retries must not be negative.")
+	}
+	if *retryBackoff < 0 {
+		usageAndExit("-retry-backoff must not be negative.")
+	}
+	var retry *loomclient.RetryPolicy
+	if *retries > 0 {
+		categories, err := loomclient.ParseRetryCategories(*retryOn)
+		if err != nil {
+			usageAndExit(fmt.Sprintf("%s in -retry-on.", err))
+		}
+		if *rawRequest {
+			for _, c := range categories {
+				if c == loomclient.ErrCategoryNonce {
+					usageAndExit("-retry-on nonce cannot be used with -raw-request, its retries resend the same signed tx.")
+				}
+			}
+		}
+		retry = &loomclient.RetryPolicy{
+			MaxAttempts: *retries + 1,
+			Backoff:     *retryBackoff,
+			Categories:  categories,
+		}
+	}
+
+	tlsConfig, err := loomclient.TLSOptions{
+		CAFile:             *caCert,
+		CertFile:           *cert,
+		KeyFile:            *certKey,
+		InsecureSkipVerify: *insecure,
+	}.Config()
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+
+	var proxyURL *gourl.URL
+	if *proxyAddr != "" {
+		addr := *proxyAddr
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		proxyURL, err = gourl.Parse(addr)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+	}
+
+	if *maxIdleConns < 0 || *maxConnsPerHost < 0 {
+		usageAndExit("-max-idle-conns and -max-conns-per-host must not be negative.")
+	}
+
+	if *h2 && (*proxyAddr != "" || *disableKeepAlives || *maxConnsPerHost > 0) {
+		usageAndExit("-proxy, -disable-keepalive and -max-conns-per-host can't be used with -h2.")
+	}
+
+	if *verifyTimeout <= 0 {
+		usageAndExit("-verify-timeout must be greater than 0.")
+	}
+
+	if *auditQPS <= 0 {
+		usageAndExit("-audit-qps must be greater than 0.")
+	}
+
+	if *auditFile != "" && !*audit {
+		usageAndExit("-audit-file requires -audit.")
+	}
+
+	if (*verify || *audit) && *transactions != requester.WorkloadWrite && *transactions != requester.WorkloadMixed {
+		usageAndExit("-verify and -audit require the write or mixed workload.")
+	}
+
+	if *ratio < 0 || *ratio > 1 {
+		usageAndExit("-o must be between 0 and 1.")
+	}
+
 	w := &requester.Work{
 		// Request:           req,
-		RequestBody:       body,
-		UseRawRequest:     *rawRequest,
-		TransactionType:   *transactions,
-		Ratio:             *ratio,
-		N:                 num,
-		C:                 conc,
-		QPS:               q,
-		Timeout:           *t,
-		WriteURL:          *writeURL,
-		ReadURL:           *readURL,
-		ChainID:           *chainID,
-		ContractAddress:   *contractAddr,
-		ContractMethod:    *contractMethod,
-		PrivateKey:        *privateKey,
-		DisableKeepAlives: *disableKeepAlives,
-		UseProgress:       true,
+		RequestBody:        body,
+		UseRawRequest:      *rawRequest,
+		UseFastJSON:        *fastJson,
+		TransactionType:    *transactions,
+		Ratio:              *ratio,
+		N:                  num,
+		C:                  conc,
+		QPS:                q,
+		Timeout:            *t,
+		Warmup:             *warmup,
+		WarmupN:            *warmupN,
+		WriteURL:           *writeURL,
+		ReadURL:            *readURL,
+		Nodes:              nodes,
+		Balance:            *balance,
+		HealthCheck:        *healthCheck,
+		Failover:           *failover,
+		Retry:              retry,
+		BatchSize:          *batch,
+		ChainID:            *chainID,
+		ContractAddress:    *contractAddr,
+		ContractMethod:     *contractMethod,
+		PrivateKey:         *privateKey,
+		DisableKeepAlives:  *disableKeepAlives,
+		DisableCompression: *disableCompression,
+		DisableRedirects:   *disableRedirects,
+		H2:                 *h2,
+		ProxyAddr:          proxyURL,
+		MaxIdleConns:       *maxIdleConns,
+		MaxConnsPerHost:    *maxConnsPerHost,
+		TLSConfig:          tlsConfig,
+		Verify:             *verify,
+		VerifyURL:          *verifyURL,
+		VerifyTimeout:      *verifyTimeout,
+		Audit:              *audit,
+		AuditQPS:           *auditQPS,
+		Interval:           *interval,
+		Output:             *format,
+		MetricsAddr:        *metricsAddr,
+		MetricsLinger:      *metricsLinger,
+		ResultLogFormat:    *logFormat,
+		Duration:           dur,
+		UseProgress:        !*live,
+	}
+
+	// Keep stdout clean for machine readable reports.
+	console := os.Stdout
+	if *format != "" && *outputFile == "" {
+		console = os.Stderr
+	}
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.Writer = f
+	}
+	if *live {
+		w.LiveWriter = console
+	}
+	if *auditFile != "" {
+		f, err := os.Create(*auditFile)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.AuditWriter = f
+	}
+	if *timeSeries != "" {
+		f, err := os.Create(*timeSeries)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.TimeSeriesWriter = f
+	}
+	if *logFile != "" {
+		f, err := os.Create(*logFile)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.ResultLog = f
+	}
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.CPUProfile = f
+	}
+	if *memProfile != "" {
+		f, err := os.Create(*memProfile)
+		if err != nil {
+			errAndExit(err.Error())
+		}
+		defer f.Close()
+		w.HeapProfile = f
 	}
 	w.Init()
 
@@ -275,40 +718,101 @@ func runCmd() {
 		<-c
 		w.Stop()
 	}()
-	if dur > 0 {
+
+	// progress bar
+	if w.UseProgress {
 		go func() {
-			time.Sleep(dur)
-			w.Stop()
+			count := num
+			bar := pb.New(count)
+			bar.Output = console
+			bar.Start()
+			for _ = range w.Progress {
+				bar.Increment()
+				// time.Sleep(time.Millisecond)
+				if cur := bar.Get(); int(cur) == count-1 {
+					break
+				}
+			}
+			bar.Increment()
+			bar.FinishPrint("Done!")
 		}()
 	}
 
-	// progress bar
-	go func() {
-		count := num
-		bar := pb.StartNew(count)
-		for _ = range w.Progress {
-			bar.Increment()
-			// time.Sleep(time.Millisecond)
-			if cur := bar.Get(); int(cur) == count-1 {
-				break
-			}
+	w.Run(context.Background())
+	if err := w.Err(); err != nil {
+		errAndExit(err.Error())
+	}
+
+	if len(asserts) > 0 {
+		report := w.Report()
+		var results []requester.AssertionResult
+		failed := false
+		for _, a := range asserts {
+			res := a.Evaluate(report)
+			failed = failed || !res.Pass
+			results = append(results, res)
 		}
-		bar.Increment()
-		bar.FinishPrint("Done!")
-	}()
+		requester.PrintAssertions(console, results)
+		if failed {
+			os.Exit(exitAssertFailed)
+		}
+	}
+}
+
+// Exit statuses set apart from the status of errAndExit and usageAndExit,
+// so that scripts can tell a failed check from a run that couldn't be made.
+const (
+	// exitRegressed is the exit status of compare when the new run
+	// regressed.
+	exitRegressed = 2
+
+	// exitAssertFailed is the exit status of a run that failed an -assert.
+	exitAssertFailed = 3
+)
 
-	w.Run()
+func compareCmd() {
+	if flag.NArg() != 2 {
+		usageAndExit("compare needs a base and a new report.")
+	}
+	base, err := requester.LoadJSONReport(flag.Arg(0))
+	if err != nil {
+		errAndExit(err.Error())
+	}
+	head, err := requester.LoadJSONReport(flag.Arg(1))
+	if err != nil {
+		errAndExit(err.Error())
+	}
+
+	cmp := requester.Compare(base, head, requester.CompareOptions{
+		RpsThreshold:     *rpsThreshold,
+		LatencyThreshold: *latencyThreshold,
+		ErrorThreshold:   *errorThreshold,
+		Alpha:            *alpha,
+	})
+	cmp.Print(os.Stdout)
+	if cmp.Verdict == requester.VerdictRegressed {
+		os.Exit(exitRegressed)
+	}
+}
+
+func isWorkload(name string) bool {
+	for _, w := range requester.Workloads() {
+		if w == name {
+			return true
+		}
+	}
+	return false
 }
 
 func errAndExit(msg string) {
-	fmt.Fprintf(os.Stderr, msg)
+	fmt.Fprint(os.Stderr, msg)
 	fmt.Fprintf(os.Stderr, "\n")
 	os.Exit(1)
 }
 
 func usageAndExit(msg string) {
 	if msg != "" {
-		fmt.Fprintf(os.Stderr, msg)
+		fmt.Fprint(os.Stderr, msg)
 		fmt.Fprintf(os.Stderr, "\n\n")
 	}
 	flag.Usage()
@@ -335,3 +839,14 @@ func usageAndExit(msg string) {
 // 	*h = append(*h, value)
 // 	return nil
 // }
+
+type assertSlice []string
+
+func (a *assertSlice) String() string {
+	return fmt.Sprintf("%s", *a)
+}
+
+func (a *assertSlice) Set(value string) error {
+	*a = append(*a, value)
+	return nil
+}
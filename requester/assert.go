@@ -0,0 +1,204 @@
+package requester
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Kinds of values a metric holds, which decide how thresholds are parsed and
+// values are printed.
+const (
+	kindNumber = iota
+	kindCount
+	kindLatency
+	kindRate
+)
+
+var assertOps = []string{"<=", ">=", "<", ">"}
+
+// Assertion is a condition on a metric of the final report, such as
+// "p99 < 2s", "error_rate < 0.1%" or "rps > 300".
+//
+// The metric is one of rps, requests, errors, error_rate, average, fastest,
+// slowest, std_dev or a percentile of the latency distribution such as p50
+// or p99.9. Latency thresholds are durations, or seconds if no unit is
+// given. Error rate thresholds are fractions, or percentages if followed
+// by "%". Latency and error rate assertions fail if the run has no
+// successful results.
+type Assertion struct {
+	Expr      string
+	Metric    string
+	Op        string
+	Threshold float64
+
+	kind int
+}
+
+// AssertionResult is the outcome of an assertion against a report.
+type AssertionResult struct {
+	*Assertion
+	Actual float64
+	Pass   bool
+
+	// NoData is set if the report has no successful results to measure a
+	// latency on, or no results to measure a rate on. The assertion fails.
+	NoData bool
+}
+
+// ParseAssertion parses an assertion of the form "<metric> <op> <value>",
+// where op is one of <, <=, > or >=.
+func ParseAssertion(expr string) (*Assertion, error) {
+	a := &Assertion{Expr: strings.TrimSpace(expr)}
+	var value string
+	for _, op := range assertOps {
+		if i := strings.Index(a.Expr, op); i >= 0 {
+			a.Metric = strings.TrimSpace(a.Expr[:i])
+			a.Op = op
+			value = strings.TrimSpace(a.Expr[i+len(op):])
+			break
+		}
+	}
+	if a.Op == "" {
+		return nil, fmt.Errorf("invalid assertion %q: missing operator", expr)
+	}
+
+	switch a.Metric {
+	case "rps":
+		a.kind = kindNumber
+	case "requests", "errors":
+		a.kind = kindCount
+	case "error_rate":
+		a.kind = kindRate
+	case "average", "fastest", "slowest", "std_dev":
+		a.kind = kindLatency
+	default:
+		if !isPercentileMetric(a.Metric) {
+			return nil, fmt.Errorf("invalid assertion %q: unknown metric %s", expr, a.Metric)
+		}
+		a.kind = kindLatency
+	}
+
+	var err error
+	switch {
+	case a.kind == kindRate && strings.HasSuffix(value, "%"):
+		a.Threshold, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		a.Threshold /= 100
+	case a.kind == kindLatency && strings.IndexAny(value, "hmnsuµ") >= 0:
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		a.Threshold = d.Seconds()
+	default:
+		a.Threshold, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertion %q: invalid value %s", expr, value)
+	}
+	return a, nil
+}
+
+// isPercentileMetric reports whether metric names one of the percentiles of
+// the latency distribution.
+func isPercentileMetric(metric string) bool {
+	if !strings.HasPrefix(metric, "p") {
+		return false
+	}
+	p, err := strconv.ParseFloat(metric[1:], 64)
+	if err != nil {
+		return false
+	}
+	for _, pctl := range pctls {
+		if p == pctl {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks the assertion against a report.
+func (a *Assertion) Evaluate(r Report) AssertionResult {
+	res := AssertionResult{Assertion: a}
+	var ok bool
+	res.Actual, ok = a.value(r)
+	if !ok {
+		res.NoData = true
+		return res
+	}
+	switch a.Op {
+	case "<":
+		res.Pass = res.Actual < a.Threshold
+	case "<=":
+		res.Pass = res.Actual <= a.Threshold
+	case ">":
+		res.Pass = res.Actual > a.Threshold
+	case ">=":
+		res.Pass = res.Actual >= a.Threshold
+	}
+	return res
+}
+
+// value returns the value of the metric in r. Latencies are only defined if
+// r has successful results, and rates if it has results, ok is false
+// otherwise.
+func (a *Assertion) value(r Report) (v float64, ok bool) {
+	var errors int
+	for _, n := range r.ErrorDist {
+		errors += n
+	}
+	if a.kind == kindLatency && r.NumRes <= int64(errors) || a.kind == kindRate && r.NumRes == 0 {
+		return 0, false
+	}
+	switch a.Metric {
+	case "rps":
+		return r.Rps, true
+	case "requests":
+		return float64(r.NumRes), true
+	case "errors":
+		return float64(errors), true
+	case "error_rate":
+		return float64(errors) / float64(r.NumRes), true
+	case "average":
+		return r.Average, true
+	case "fastest":
+		return r.Fastest, true
+	case "slowest":
+		return r.Slowest, true
+	case "std_dev":
+		return r.StdDev, true
+	}
+	p, _ := strconv.ParseFloat(a.Metric[1:], 64)
+	return percentile(r.LatencyDistribution, p)
+}
+
+func (a *Assertion) format(v float64) string {
+	switch a.kind {
+	case kindCount:
+		return fmt.Sprintf("%d", int64(v))
+	case kindLatency:
+		return fmt.Sprintf("%4.4f secs", v)
+	case kindRate:
+		return fmt.Sprintf("%.4f%%", v*100)
+	}
+	return fmt.Sprintf("%4.4f", v)
+}
+
+// PrintAssertions writes the results of assertions as a pass/fail table.
+func PrintAssertions(w io.Writer, results []AssertionResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nAssertions:\n")
+	for _, res := range results {
+		verdict := "FAIL"
+		if res.Pass {
+			verdict = "PASS"
+		}
+		actual := "no data"
+		if !res.NoData {
+			actual = res.format(res.Actual)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", res.Expr, actual, verdict)
+	}
+	tw.Flush()
+}
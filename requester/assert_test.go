@@ -0,0 +1,171 @@
+package requester
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		expr      string
+		metric    string
+		op        string
+		threshold float64
+	}{
+		{"p99 < 2s", "p99", "<", 2},
+		{"p99.9<=250ms", "p99.9", "<=", 0.25},
+		{"  average < 1.5m ", "average", "<", 90},
+		{"fastest >= 500us", "fastest", ">=", 0.0005},
+		{"slowest < 3", "slowest", "<", 3},
+		{"std_dev < 1h", "std_dev", "<", 3600},
+		{"error_rate < 0.1%", "error_rate", "<", 0.001},
+		{"error_rate <= 0.05", "error_rate", "<=", 0.05},
+		{"rps > 300", "rps", ">", 300},
+		{"requests >= 1e4", "requests", ">=", 10000},
+		{"errors < 1", "errors", "<", 1},
+	}
+	for _, tt := range tests {
+		a, err := ParseAssertion(tt.expr)
+		if err != nil {
+			t.Errorf("ParseAssertion(%q): %v", tt.expr, err)
+			continue
+		}
+		if a.Metric != tt.metric || a.Op != tt.op || a.Threshold != tt.threshold {
+			t.Errorf("ParseAssertion(%q) = %s %s %v, want %s %s %v", tt.expr,
+				a.Metric, a.Op, a.Threshold, tt.metric, tt.op, tt.threshold)
+		}
+	}
+}
+
+func TestParseAssertionErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"p99 2s", "missing operator"},
+		{"", "missing operator"},
+		{"p98 < 2s", "unknown metric"},
+		{"latency < 2s", "unknown metric"},
+		{"< 2s", "unknown metric"},
+		{"p99 < fast", "invalid value"},
+		{"p99 < 2 secs", "invalid value"},
+		{"p99 <", "invalid value"},
+		{"rps > 300/s", "invalid value"},
+		{"rps > 10%", "invalid value"},
+		{"error_rate < 1s", "invalid value"},
+		{"requests > 10ms", "invalid value"},
+	}
+	for _, tt := range tests {
+		_, err := ParseAssertion(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseAssertion(%q) error = %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	report := Report{
+		Rps:       250,
+		NumRes:    1000,
+		Average:   0.2,
+		Fastest:   0.01,
+		Slowest:   3,
+		StdDev:    0.1,
+		ErrorDist: map[string]int{"timeout": 5},
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 0.15},
+			{Percentage: 99, Latency: 1.5},
+		},
+	}
+	tests := []struct {
+		expr   string
+		actual float64
+		pass   bool
+	}{
+		{"p99 < 2s", 1.5, true},
+		{"p99 < 1500ms", 1.5, false},
+		{"p99 <= 1500ms", 1.5, true},
+		{"p50 > 100ms", 0.15, true},
+		{"average < 150ms", 0.2, false},
+		{"slowest >= 3s", 3, true},
+		{"rps > 300", 250, false},
+		{"rps >= 250", 250, true},
+		{"requests >= 1000", 1000, true},
+		{"errors < 5", 5, false},
+		{"error_rate < 1%", 0.005, true},
+		{"error_rate > 0.01", 0.005, false},
+	}
+	for _, tt := range tests {
+		a, err := ParseAssertion(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := a.Evaluate(report)
+		if res.NoData || res.Actual != tt.actual || res.Pass != tt.pass {
+			t.Errorf("%q: actual %v, pass %v, no data %v, want %v, %v, false",
+				tt.expr, res.Actual, res.Pass, res.NoData, tt.actual, tt.pass)
+		}
+	}
+}
+
+func TestEvaluateNoData(t *testing.T) {
+	empty := Report{}
+	allFailed := Report{
+		Rps:       100,
+		NumRes:    100,
+		ErrorDist: map[string]int{"timeout": 60, "connection refused": 40},
+	}
+	tests := []struct {
+		expr   string
+		report Report
+		noData bool
+		pass   bool
+	}{
+		{"p99 < 2s", empty, true, false},
+		{"p50 > 0", empty, true, false},
+		{"average < 1s", empty, true, false},
+		{"fastest >= 0", empty, true, false},
+		{"slowest < 1h", empty, true, false},
+		{"std_dev < 1s", empty, true, false},
+		{"error_rate < 100%", empty, true, false},
+		{"error_rate >= 0", empty, true, false},
+		{"rps >= 0", empty, false, true},
+		{"requests >= 0", empty, false, true},
+		{"errors >= 0", empty, false, true},
+
+		{"p99 < 2s", allFailed, true, false},
+		{"p50 > 0", allFailed, true, false},
+		{"average < 1s", allFailed, true, false},
+		{"fastest >= 0", allFailed, true, false},
+		{"slowest < 1h", allFailed, true, false},
+		{"std_dev < 1s", allFailed, true, false},
+		// Every request failed: the error rate is 1.
+		{"error_rate < 100%", allFailed, false, false},
+		{"error_rate >= 1", allFailed, false, true},
+		{"rps >= 0", allFailed, false, true},
+		{"requests >= 0", allFailed, false, true},
+		{"errors >= 100", allFailed, false, true},
+	}
+	for _, tt := range tests {
+		a, err := ParseAssertion(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := a.Evaluate(tt.report)
+		if res.NoData != tt.noData || res.Pass != tt.pass {
+			t.Errorf("%q on %d results: no data %v, pass %v, want %v, %v",
+				tt.expr, tt.report.NumRes, res.NoData, res.Pass, tt.noData, tt.pass)
+		}
+	}
+	if a, _ := ParseAssertion("error_rate < 1%"); a.Evaluate(allFailed).Actual != 1 {
+		t.Errorf("error rate of a run where every request failed = %v, want 1", a.Evaluate(allFailed).Actual)
+	}
+
+	a, _ := ParseAssertion("p99 < 2s")
+	var buf bytes.Buffer
+	PrintAssertions(&buf, []AssertionResult{a.Evaluate(Report{})})
+	if out := buf.String(); !strings.Contains(out, "no data") || !strings.Contains(out, "FAIL") {
+		t.Errorf("PrintAssertions() = %q, want no data and FAIL", out)
+	}
+}
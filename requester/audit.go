@@ -0,0 +1,178 @@
+package requester
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+	"github.com/loomnetwork/go-loom/auth"
+	"golang.org/x/crypto/ed25519"
+)
+
+// auditConcurrency is the number of reads of the audit in flight.
+const auditConcurrency = 8
+
+// Default rate of the reads of the audit, in queries per second.
+const defaultAuditQPS = 100
+
+// Results of the audit of a write.
+const (
+	auditFound      = "found"
+	auditMissing    = "missing"
+	auditMismatched = "mismatched"
+	auditFailed     = "error"
+)
+
+// auditEntry is a successful write to audit.
+type auditEntry struct {
+	op     Verifiable
+	txHash string
+	height int64
+}
+
+// AuditReport summarizes the audit of the successful writes of a run, read
+// back once the run is over.
+type AuditReport struct {
+	// Writes is the number of writes to audit, Found the number read back
+	// with the written value, Missing the number not found, Mismatched the
+	// number found with another value, Errors the number that couldn't be
+	// read back and Unchecked the number left when the audit was stopped.
+	Writes     int64 `json:"writes"`
+	Found      int64 `json:"found"`
+	Missing    int64 `json:"missing"`
+	Mismatched int64 `json:"mismatched"`
+	Errors     int64 `json:"errors"`
+	Unchecked  int64 `json:"unchecked"`
+
+	// Durability is the fraction of the writes checked that were found.
+	Durability float64 `json:"durability"`
+
+	Total time.Duration `json:"total"`
+}
+
+// audit reads back every write of entries from the first node, and writes
+// the ones that are missing or mismatched to AuditWriter.
+func (b *Work) audit(ctx context.Context, entries []auditEntry) (*AuditReport, error) {
+	_, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	httpclient := &http.Client{
+		Transport: &http.Transport{MaxIdleConnsPerHost: auditConcurrency, TLSClientConfig: b.TLSConfig},
+		Timeout:   time.Duration(b.Timeout) * time.Second,
+	}
+	// Clients aren't safe for concurrent use, every reader gets its own.
+	clients := make([]*loomclient.ContractClient, auditConcurrency)
+	for i := range clients {
+		clients[i], err = b.createVerifyClient(ctx, httpclient, auth.NewEd25519Signer(privKey), b.nodes()[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	qps := b.AuditQPS
+	if qps <= 0 {
+		qps = defaultAuditQPS
+	}
+	// NewTicker panics on a zero interval, which any qps over 1e9 gives.
+	interval := time.Duration(float64(time.Second) / qps)
+	if interval < 1 {
+		interval = 1
+	}
+	throttle := time.NewTicker(interval)
+	defer throttle.Stop()
+
+	var w *csv.Writer
+	if b.AuditWriter != nil {
+		w = csv.NewWriter(b.AuditWriter)
+		w.Write([]string{"key", "result", "tx-hash", "height", "error"})
+	}
+
+	report := &AuditReport{Writes: int64(len(entries))}
+	start := time.Now()
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		next int
+	)
+	wg.Add(auditConcurrency)
+	for _, c := range clients {
+		go func(c *loomclient.ContractClient) {
+			defer wg.Done()
+			for {
+				select {
+				case <-throttle.C:
+				case <-ctx.Done():
+					return
+				}
+				mu.Lock()
+				if next == len(entries) {
+					mu.Unlock()
+					return
+				}
+				e := entries[next]
+				next++
+				mu.Unlock()
+
+				res, err := auditWrite(ctx, c, e.op)
+				if ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				switch res {
+				case auditFound:
+					report.Found++
+				case auditMissing:
+					report.Missing++
+				case auditMismatched:
+					report.Mismatched++
+				case auditFailed:
+					report.Errors++
+				}
+				if w != nil && res != auditFound {
+					var msg string
+					if err != nil {
+						msg = err.Error()
+					}
+					w.Write([]string{e.op.Key(), res, e.txHash, strconv.FormatInt(e.height, 10), msg})
+				}
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	report.Total = time.Since(start)
+	report.Unchecked = report.Writes - report.Found - report.Missing - report.Mismatched - report.Errors
+	if checked := report.Found + report.Missing + report.Mismatched; checked > 0 {
+		report.Durability = float64(report.Found) / float64(checked)
+	}
+	if w != nil {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// auditWrite reads back the write of op once. A read that fails with an
+// error from the node, such as the contract not finding the key, counts the
+// write as missing, other errors as a failed read.
+func auditWrite(ctx context.Context, c *loomclient.ContractClient, op Verifiable) (string, error) {
+	visible, match, err := op.ReadBack(ctx, c)
+	switch {
+	case match:
+		return auditFound, nil
+	case visible:
+		return auditMismatched, nil
+	case err == nil || loomclient.ErrorCategory(err) == loomclient.ErrCategoryRPC:
+		return auditMissing, err
+	default:
+		return auditFailed, err
+	}
+}
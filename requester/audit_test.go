@@ -0,0 +1,151 @@
+package requester
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+var (
+	// errRPCNotFound is the error of the contract not finding a key.
+	errRPCNotFound = &loomclient.ResponseError{RPCError: &loomclient.RPCError{Code: -32603, Message: "not found"}}
+
+	// errUnreachable is the error of a read that didn't reach the node.
+	errUnreachable = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+)
+
+func TestAuditWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		read fakeRead
+		want string
+		err  error
+	}{
+		{"found", readMatch, auditFound, nil},
+		{"mismatched", readMismatch, auditMismatched, nil},
+		{"empty", fakeRead{}, auditMissing, nil},
+		{"not found", fakeRead{err: errRPCNotFound}, auditMissing, errRPCNotFound},
+		{"unreachable", fakeRead{err: errUnreachable}, auditFailed, errUnreachable},
+		{"other error", fakeRead{err: errNotFound}, auditFailed, errNotFound},
+	}
+	for _, tt := range tests {
+		got, err := auditWrite(context.Background(), nil, &fakeWrite{reads: []fakeRead{tt.read}})
+		if got != tt.want || err != tt.err {
+			t.Errorf("%s: auditWrite() = %s, %v, want %s, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// auditWork returns a run auditing its writes from an unreachable node, with
+// the fake writes never making a request.
+func auditWork(qps float64, out *bytes.Buffer) *Work {
+	b := &Work{
+		Audit:           true,
+		AuditQPS:        qps,
+		ChainID:         "default",
+		ContractAddress: "0x005B17864f3adbF53b1384F2E6f2120c6652F779",
+		WriteURL:        "http://127.0.0.1:1/rpc",
+		ReadURL:         "http://127.0.0.1:1/query",
+	}
+	if out != nil {
+		b.AuditWriter = out
+	}
+	return b
+}
+
+func TestAudit(t *testing.T) {
+	var entries []auditEntry
+	add := func(n int, read fakeRead) {
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key-%d", len(entries))
+			op := &fakeWrite{key: key, reads: []fakeRead{read}}
+			entries = append(entries, auditEntry{op: op, txHash: "HASH-" + key, height: int64(len(entries) + 1)})
+		}
+	}
+	add(6, readMatch)
+	add(2, readMismatch)
+	add(1, fakeRead{err: errRPCNotFound})
+	add(1, fakeRead{})
+	add(2, fakeRead{err: errUnreachable})
+
+	var out bytes.Buffer
+	b := auditWork(1e6, &out)
+	report, err := b.audit(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AuditReport{Writes: 12, Found: 6, Missing: 2, Mismatched: 2, Errors: 2}
+	got := *report
+	got.Durability, got.Total = 0, 0
+	if got != want {
+		t.Errorf("audit = %+v, want %+v", got, want)
+	}
+	// Writes that couldn't be read back don't count against durability.
+	if report.Durability != 0.6 {
+		t.Errorf("durability = %v, want 0.6", report.Durability)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 || !reflect.DeepEqual(rows[0], []string{"key", "result", "tx-hash", "height", "error"}) {
+		t.Fatalf("csv header = %v", rows)
+	}
+	byKey := make(map[string][]string)
+	for _, row := range rows[1:] {
+		byKey[row[0]] = row
+	}
+	wantRows := [][]string{
+		{"key-6", auditMismatched, "HASH-key-6", "7", ""},
+		{"key-7", auditMismatched, "HASH-key-7", "8", ""},
+		{"key-8", auditMissing, "HASH-key-8", "9", errRPCNotFound.Error()},
+		{"key-9", auditMissing, "HASH-key-9", "10", ""},
+		{"key-10", auditFailed, "HASH-key-10", "11", errUnreachable.Error()},
+		{"key-11", auditFailed, "HASH-key-11", "12", errUnreachable.Error()},
+	}
+	if len(rows) != len(wantRows)+1 {
+		t.Errorf("%d csv rows, want %d: %v", len(rows)-1, len(wantRows), rows)
+	}
+	for _, row := range wantRows {
+		if !reflect.DeepEqual(byKey[row[0]], row) {
+			t.Errorf("csv row of %s = %v, want %v", row[0], byKey[row[0]], row)
+		}
+	}
+}
+
+func TestAuditStopped(t *testing.T) {
+	var entries []auditEntry
+	for i := 0; i < 100; i++ {
+		entries = append(entries, auditEntry{op: &fakeWrite{reads: []fakeRead{readMatch}}})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	report, err := auditWork(50, nil).audit(ctx, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Found == 0 || report.Unchecked == 0 {
+		t.Fatalf("audit = %+v, want some writes found and some unchecked", report)
+	}
+	if report.Found+report.Unchecked != 100 || report.Durability != 1 {
+		t.Errorf("audit = %+v, want the found and unchecked writes to add up to 100, and a durability of 1", report)
+	}
+
+	// An audit stopped before it started checked nothing.
+	report, err = auditWork(50, nil).audit(ctx, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchecked != 100 || report.Durability != 0 {
+		t.Errorf("audit = %+v, want 100 unchecked writes and a durability of 0", report)
+	}
+}
@@ -0,0 +1,194 @@
+package requester
+
+import (
+	"context"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// runBatches makes the n requests of the worker of ws in batches of
+// BatchSize, each sent to the node picked for its first request. With a QPS,
+// every request of a batch waits for its own tick.
+func (b *Work) runBatches(ctx context.Context, ws *WorkerState, workload Workload, tracer *callTracer, throttle <-chan time.Time, sticky, n int) {
+	ops := make([]Op, 0, b.batchSize())
+	for i := 0; i < n; i += len(ops) {
+		ops = ops[:0]
+		for len(ops) < b.batchSize() && i+len(ops) < n {
+			if throttle != nil {
+				select {
+				case <-throttle:
+				case <-ctx.Done():
+					return
+				}
+			}
+			ops = append(ops, workload.Next(ws))
+			ws.Seq++
+		}
+		// Check if application is stopped. Do not send into a closed channel.
+		if ctx.Err() != nil {
+			return
+		}
+		ws.use(b.pickNode(ws, ws.nodes, sticky))
+		b.makeBatch(ctx, ws, ops, tracer)
+	}
+}
+
+// makeBatch performs ops in JSON-RPC batches: one of the txs and one of the
+// queries among them. Ops that can't be batched are made one at a time.
+// Batched ops are neither retried nor failed over.
+func (b *Work) makeBatch(ctx context.Context, ws *WorkerState, ops []Op, tracer *callTracer) {
+	var txs, queries []BatchOp
+	var txCalls, queryCalls []*loomclient.ContractCall
+	for _, op := range ops {
+		bop, ok := op.(BatchOp)
+		if !ok {
+			b.makeRequest(ctx, ws, op, tracer, 0)
+			continue
+		}
+		call, tx := bop.Call()
+		if tx {
+			txs, txCalls = append(txs, bop), append(txCalls, call)
+		} else {
+			queries, queryCalls = append(queries, bop), append(queryCalls, call)
+		}
+	}
+	if len(txs) > 0 {
+		b.sendBatch(ctx, ws, txs, txCalls, tracer, ws.Client.CallBatch)
+	}
+	if len(queries) > 0 {
+		b.sendBatch(ctx, ws, queries, queryCalls, tracer, ws.Client.StaticCallBatch)
+	}
+}
+
+// sendBatch sends calls, the calls of ops, with send and reports a result
+// for every op. The results share the duration of the batch, and the
+// connection phases and auxiliary calls are those of the first.
+func (b *Work) sendBatch(ctx context.Context, ws *WorkerState, ops []BatchOp, calls []*loomclient.ContractCall, tracer *callTracer,
+	send func(context.Context, []*loomclient.ContractCall) error) {
+	s := now()
+	warmups := b.warmupN(s, len(ops))
+	// The batch is reported with its first request after the warmup, if it
+	// has some, counting only the requests after the warmup.
+	first := warmups % len(ops)
+	if b.metrics != nil {
+		b.metrics.inFlight.Add(float64(len(ops)))
+	}
+	send(ctx, calls)
+	t := now()
+	if b.metrics != nil {
+		b.metrics.inFlight.Sub(float64(len(ops)))
+	}
+	phases, aux := tracer.take()
+	var account string
+	if b.ResultLog != nil || b.OnResult != nil {
+		account = ws.Client.GetCallerAddress().String()
+	}
+	for i, op := range ops {
+		err := calls[i].Err
+		warmup := i < warmups
+		var written Verifiable
+		if err == nil {
+			if v, ok := op.(Verifiable); ok {
+				if b.Verify && !warmup {
+					b.queueVerify(ctx, ws, v, t)
+				}
+				if b.Audit {
+					written = v
+				}
+			}
+		}
+		res := &result{
+			statusCode: 200, // TODO: Get stausCoec from Loom Call
+			duration:   t - s,
+			err:        err,
+			category:   classify(op, err),
+			cancelled:  err != nil && ctx.Err() != nil,
+			op:         op.Name(),
+			node:       ws.Node,
+			attempts:   1,
+			batched:    true,
+			start:      s,
+			warmup:     warmup,
+			written:    written,
+			account:    account,
+			nonce:      calls[i].Commit.Nonce,
+			txHash:     calls[i].Commit.Hash,
+			height:     calls[i].Commit.Height,
+		}
+		if i == first {
+			res.batchSize = len(ops) - first
+			res.connDuration = phases.connDuration
+			res.dnsDuration = phases.dnsDuration
+			res.dialDuration = phases.dialDuration
+			res.tlsDuration = phases.tlsDuration
+			res.reqDuration = phases.reqDuration
+			res.resDuration = phases.resDuration
+			res.delayDuration = phases.delayDuration
+			res.gotConn = phases.gotConn
+			res.connReused = phases.connReused
+			res.aux = aux
+		}
+		b.results <- res
+
+		if b.UseProgress {
+			b.Progress <- struct{}{}
+		}
+	}
+}
+
+// batchStats aggregates the batches of a run with a batch size.
+type batchStats struct {
+	batches int64
+	items   int64
+	total   float64       // sum of the durations of the batches, in seconds
+	lats    *hdrHistogram // latency of the batches
+}
+
+// add counts the batch res is the first result of.
+func (r *batchStats) add(res *result) {
+	if res.batchSize == 0 {
+		return
+	}
+	r.batches++
+	r.items += int64(res.batchSize)
+	r.total += res.duration.Seconds()
+	r.lats.record(res.duration)
+}
+
+func (r *batchStats) report() *BatchReport {
+	rep := &BatchReport{
+		Batches:             r.batches,
+		Items:               r.items,
+		Average:             r.lats.mean(),
+		Slowest:             r.lats.max.Seconds(),
+		LatencyDistribution: r.lats.distribution(pctls),
+	}
+	if r.batches > 0 {
+		rep.AverageSize = float64(r.items) / float64(r.batches)
+	}
+	if r.items > 0 {
+		rep.PerItem = r.total / float64(r.items)
+	}
+	return rep
+}
+
+// BatchReport summarizes the JSON-RPC batches of a run with a batch size.
+type BatchReport struct {
+	// Batches is the number of batches sent, Items the number of requests
+	// sent in them and AverageSize the average number of requests per
+	// batch.
+	Batches     int64   `json:"batches"`
+	Items       int64   `json:"items"`
+	AverageSize float64 `json:"average_size"`
+
+	// Average, Slowest and LatencyDistribution describe the latency of the
+	// batches, in seconds.
+	Average             float64               `json:"average"`
+	Slowest             float64               `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+
+	// PerItem is the latency of the batches amortized over their requests,
+	// in seconds.
+	PerItem float64 `json:"per_item"`
+}
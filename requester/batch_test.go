@@ -0,0 +1,48 @@
+package requester
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWarmupN(t *testing.T) {
+	tests := []struct {
+		name    string
+		warmupN int
+		warmup  time.Duration
+		batches []int
+		want    []int
+	}{
+		{"none", 0, 0, []int{10, 10}, []int{0, 0}},
+		{"requests", 25, 0, []int{10, 10, 10, 10}, []int{10, 10, 5, 0}},
+		{"single requests", 2, 0, []int{1, 1, 1}, []int{1, 1, 0}},
+		{"exact", 20, 0, []int{10, 10, 10}, []int{10, 10, 0}},
+		{"duration", 0, time.Hour, []int{10, 1}, []int{10, 1}},
+		{"requests and duration", 5, time.Hour, []int{10, 10}, []int{10, 10}},
+	}
+	for _, tt := range tests {
+		b := &Work{WarmupN: tt.warmupN, Warmup: tt.warmup, start: now()}
+		var got []int
+		for _, k := range tt.batches {
+			got = append(got, b.warmupN(now(), k))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: warmupN() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// WarmupN counts requests, not batches: 100 warmup requests are 10
+	// batches of 10.
+	b := &Work{WarmupN: 100, start: now()}
+	var warmups int
+	for i := 0; i < 20; i++ {
+		warmups += b.warmupN(now(), 10)
+	}
+	if warmups != 100 {
+		t.Errorf("%d warmup requests in batches of 10, want 100", warmups)
+	}
+	if b.isWarmup(now()) {
+		t.Error("request after the warmup requests is a warmup request")
+	}
+}
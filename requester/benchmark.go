@@ -0,0 +1,425 @@
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jsimnz/loombench/loomclient"
+	"github.com/jsimnz/loombench/types"
+)
+
+// Config configures a Benchmark. The zero value of an optional field selects
+// the same default as the loombench command.
+type Config struct {
+	// WriteURL is the URL txs are submitted to.
+	// Default is "http://localhost:46658/rpc".
+	WriteURL string
+
+	// ReadURL is the URL state is queried from.
+	// Default is "http://localhost:46658/query".
+	ReadURL string
+
+	// Nodes, if set, are the nodes to distribute requests across instead of
+	// WriteURL and ReadURL, see ParseNodes. Balance is how, one of the
+	// Balance strategies. Default is BalanceRoundRobin.
+	Nodes   []Node
+	Balance string
+
+	// HealthCheck, if non-zero, is the time between the health checks of
+	// the nodes, which eject the failing ones from rotation. Failover
+	// retries requests that couldn't reach their node once on another. It
+	// can't be used with UseRawRequest.
+	HealthCheck time.Duration
+	Failover    bool
+
+	// Retry, if set, is the policy failed requests are retried with. Raw
+	// requests are retried with the same signed tx, so nonce errors can't
+	// be retried with UseRawRequest.
+	Retry *loomclient.RetryPolicy
+
+	// BatchSize, if greater than one, is the number of requests of a
+	// worker sent together in a JSON-RPC batch.
+	BatchSize int
+
+	// ChainID is the ID of the Loom chain. Default is "default".
+	ChainID string
+
+	// ContractAddress is the name or address of the contract to call.
+	// Default is "SimpleStore".
+	ContractAddress string
+
+	// ContractMethod is the method to call on the contract. Default is "Set".
+	ContractMethod string
+
+	// RequestBody is the argument of the method. Default is a
+	// LoomBenchWriteTx setting "hello" to "world".
+	RequestBody proto.Message
+
+	// Workload is the name of the workload generating the operations, one
+	// of Workloads(). Default is WorkloadCall, which calls ContractMethod
+	// with RequestBody.
+	Workload string
+
+	// Ratio is the fraction of operations that are reads, for workloads
+	// mixing reads and writes.
+	Ratio float64
+
+	// PrivateKey is the path of a base64 encoded private key to sign txs
+	// with, or "genkey" to sign with a new key per worker. Default is
+	// "genkey".
+	PrivateKey string
+
+	// UseRawRequest crafts the signed tx of each request ahead of time.
+	UseRawRequest bool
+
+	// UseFastJSON encodes and decodes the raw requests with the easyjson
+	// marshalers, requires UseRawRequest.
+	UseFastJSON bool
+
+	// N is the number of requests to make. It is required unless Duration
+	// is set, in which case it is ignored.
+	N int
+
+	// Duration is the length of the run. If non-zero, requests are made
+	// until it elapses.
+	Duration time.Duration
+
+	// C is the number of concurrent workers. Default is 1.
+	C int
+
+	// QPS is the rate limit of each worker in queries per second. Default is
+	// no limit.
+	QPS float64
+
+	// Timeout is the timeout of each call, rounded up to whole seconds.
+	// Default is no timeout.
+	Timeout time.Duration
+
+	// Warmup and WarmupN exclude the requests made in the first Warmup of
+	// the run, or the first WarmupN requests, from the report.
+	Warmup  time.Duration
+	WarmupN int
+
+	// Interval is the time between the snapshots of Report.Intervals.
+	// Default is 1s.
+	Interval time.Duration
+
+	// DisableKeepAlives prevents the reuse of connections between requests.
+	DisableKeepAlives bool
+
+	// DisableCompression and DisableRedirects disable the compression of
+	// responses and the following of HTTP redirects.
+	DisableCompression bool
+	DisableRedirects   bool
+
+	// H2 makes HTTP/2 requests, negotiated with TLS to https:// nodes and
+	// with prior knowledge (h2c) to the others. It can't be used with
+	// Proxy, DisableKeepAlives or MaxConnsPerHost, which h2c ignores.
+	H2 bool
+
+	// Proxy is the HTTP proxy requests are sent through. Optional.
+	Proxy *url.URL
+
+	// MaxIdleConns is the number of idle connections kept open to each
+	// node, default C up to 500, and MaxConnsPerHost the limit of
+	// connections to each node, default none.
+	MaxIdleConns    int
+	MaxConnsPerHost int
+
+	// TLSConfig is the TLS configuration of the connections to https://
+	// nodes, see loomclient.TLSOptions. Optional.
+	TLSConfig *tls.Config
+
+	// Verify reads back every successful write until the written value is
+	// visible, from VerifyURL if set, and summarizes the outcome in
+	// Report.Verify. A write not visible within VerifyTimeout, 10s by
+	// default, counts as lost. The writes are read back in the background,
+	// without slowing down the workers.
+	Verify        bool
+	VerifyURL     string
+	VerifyTimeout time.Duration
+
+	// Audit reads back every successful write once the run is over, at
+	// AuditQPS reads per second, 100 by default, and summarizes the outcome
+	// in Report.Audit. AuditWriter, if non-nil, receives the writes that
+	// weren't found as csv rows.
+	Audit       bool
+	AuditQPS    float64
+	AuditWriter io.Writer
+
+	// OnResult, if non-nil, is called with every result as it arrives,
+	// including warmup and cancelled requests. Calls are made one at a time
+	// from a single goroutine, so OnResult needs no locking, but it should
+	// return quickly: results queue up while it runs.
+	OnResult func(Result)
+}
+
+// Result is the outcome of a single request.
+type Result struct {
+	// Op is the operation performed, the name of the contract method.
+	Op string
+
+	// Node is the name of the node the request was sent to, and
+	// FailedOver is set if it was retried there after failing on another.
+	Node       string
+	FailedOver bool
+
+	// Attempts is the number of times the request was made, more than 1
+	// if it was retried.
+	Attempts int
+
+	// Start is the time the request was sent.
+	Start time.Time
+
+	// Duration is the time the request took, including auxiliary calls
+	// such as nonce lookups.
+	Duration time.Duration
+
+	// Warmup is set if the request was made during the warmup period and
+	// is excluded from the report.
+	Warmup bool
+
+	// Cancelled is set if the request was aborted by the end of the run.
+	Cancelled bool
+
+	// Err is the error of the request, if it failed, and Category the
+	// category of Err, as classified by the op or loomclient.ErrorCategory.
+	Err      error
+	Category string
+
+	// Chain identifiers of the tx, if it was committed. Nonce is empty if
+	// the tx failed before it was signed.
+	Account string
+	Nonce   uint64
+	TxHash  string
+	Height  int64
+}
+
+// Benchmark is a load test that is run from Go code rather than the
+// loombench command, for example from an integration test. Unlike the
+// command, it doesn't print anything or exit the process.
+//
+// A benchmark of 1000 writes by 10 workers that fails a test if more than
+// 1% of them fail:
+//
+//	bm, err := requester.New(requester.Config{
+//		WriteURL: node.WriteURL,
+//		ReadURL:  node.ReadURL,
+//		N:        1000,
+//		C:        10,
+//	})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	report, err := bm.Run(ctx)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	a, _ := requester.ParseAssertion("error_rate<=1%")
+//	if res := a.Evaluate(*report); !res.Pass {
+//		t.Errorf("error rate %v, want %s", res.Actual, a.Expr)
+//	}
+//
+// Results can be streamed as they arrive, for example to collect the hashes
+// of the committed txs:
+//
+//	var hashes []string
+//	bm, err := requester.New(requester.Config{
+//		Duration: 30 * time.Second,
+//		C:        4,
+//		OnResult: func(res requester.Result) {
+//			if res.Err == nil && !res.Warmup {
+//				hashes = append(hashes, res.TxHash)
+//			}
+//		},
+//	})
+//
+// A Benchmark can be run more than once, each run makes a new set of
+// requests.
+type Benchmark struct {
+	cfg Config
+}
+
+// New returns a Benchmark configured by cfg, or an error if cfg is invalid.
+func New(cfg Config) (*Benchmark, error) {
+	if cfg.C == 0 {
+		cfg.C = 1
+	}
+	if cfg.C < 0 {
+		return nil, errors.New("requester: C cannot be smaller than 1")
+	}
+	if cfg.Duration < 0 || cfg.Timeout < 0 || cfg.Interval < 0 || cfg.VerifyTimeout < 0 {
+		return nil, errors.New("requester: Duration, Timeout, Interval and VerifyTimeout cannot be negative")
+	}
+	if cfg.Warmup < 0 || cfg.WarmupN < 0 {
+		return nil, errors.New("requester: Warmup and WarmupN cannot be negative")
+	}
+	if cfg.Duration > 0 {
+		if cfg.Warmup >= cfg.Duration {
+			return nil, errors.New("requester: Warmup must be shorter than Duration")
+		}
+	} else {
+		if cfg.N <= 0 {
+			return nil, errors.New("requester: N cannot be smaller than 1 unless Duration is set")
+		}
+		if cfg.N < cfg.C {
+			return nil, errors.New("requester: N cannot be less than C")
+		}
+		if cfg.WarmupN >= cfg.N {
+			return nil, errors.New("requester: WarmupN must be less than N")
+		}
+	}
+	if cfg.Workload == "" {
+		cfg.Workload = WorkloadCall
+	}
+	if cfg.UseRawRequest && cfg.Workload != WorkloadCall {
+		return nil, errors.New("requester: UseRawRequest can only be used with the call workload")
+	}
+	if cfg.H2 && (cfg.Proxy != nil || cfg.DisableKeepAlives || cfg.MaxConnsPerHost > 0) {
+		return nil, errors.New("requester: Proxy, DisableKeepAlives and MaxConnsPerHost cannot be used with H2")
+	}
+	if cfg.Failover && cfg.UseRawRequest {
+		return nil, errors.New("requester: Failover cannot be used with UseRawRequest")
+	}
+	if cfg.Retry != nil && cfg.UseRawRequest {
+		for _, c := range cfg.Retry.Categories {
+			if c == loomclient.ErrCategoryNonce {
+				return nil, errors.New("requester: Retry cannot retry nonce errors with UseRawRequest, which resends the same signed tx")
+			}
+		}
+	}
+	if cfg.UseFastJSON && !cfg.UseRawRequest {
+		return nil, errors.New("requester: UseFastJSON requires UseRawRequest")
+	}
+	if cfg.Ratio < 0 || cfg.Ratio > 1 {
+		return nil, errors.New("requester: Ratio must be between 0 and 1")
+	}
+	switch cfg.Balance {
+	case "", BalanceRoundRobin, BalanceRandom, BalanceWeighted, BalanceSticky:
+	default:
+		return nil, fmt.Errorf("requester: unknown Balance %q", cfg.Balance)
+	}
+	if cfg.HealthCheck < 0 {
+		return nil, errors.New("requester: HealthCheck must not be negative")
+	}
+	if cfg.WriteURL == "" {
+		cfg.WriteURL = "http://localhost:46658/rpc"
+	}
+	if cfg.ReadURL == "" {
+		cfg.ReadURL = "http://localhost:46658/query"
+	}
+	if cfg.ChainID == "" {
+		cfg.ChainID = "default"
+	}
+	if cfg.ContractAddress == "" {
+		cfg.ContractAddress = "SimpleStore"
+	}
+	if cfg.ContractMethod == "" {
+		cfg.ContractMethod = "Set"
+	}
+	if cfg.RequestBody == nil {
+		cfg.RequestBody = &types.LoomBenchWriteTx{
+			Key: []byte("hello"),
+			Val: []byte("world"),
+		}
+	}
+	if cfg.PrivateKey == "" {
+		cfg.PrivateKey = "genkey"
+	}
+	return &Benchmark{cfg: cfg}, nil
+}
+
+// Run makes the requests of the benchmark and returns its report. It blocks
+// until all requests are made, the Duration elapses or ctx is done; requests
+// in flight when ctx is done are aborted and counted in
+// Report.NumCancelled, and the report of the requests made so far is
+// returned.
+//
+// Failed requests don't make Run fail, they are counted in the report. Run
+// returns an error if the workers can't be set up, such as when the private
+// key can't be read or the contract can't be resolved, or if the writes
+// can't be audited or the profiles or the per-request log can't be written.
+func (bm *Benchmark) Run(ctx context.Context) (*Report, error) {
+	cfg := bm.cfg
+	n := cfg.N
+	if cfg.Duration > 0 {
+		n = math.MaxInt32
+	}
+	w := &Work{
+		RequestBody:        cfg.RequestBody,
+		UseRawRequest:      cfg.UseRawRequest,
+		UseFastJSON:        cfg.UseFastJSON,
+		TransactionType:    cfg.Workload,
+		Ratio:              cfg.Ratio,
+		N:                  n,
+		C:                  cfg.C,
+		QPS:                cfg.QPS,
+		Timeout:            int((cfg.Timeout + time.Second - 1) / time.Second),
+		Duration:           cfg.Duration,
+		Warmup:             cfg.Warmup,
+		WarmupN:            cfg.WarmupN,
+		Interval:           cfg.Interval,
+		WriteURL:           cfg.WriteURL,
+		ReadURL:            cfg.ReadURL,
+		Nodes:              cfg.Nodes,
+		Balance:            cfg.Balance,
+		HealthCheck:        cfg.HealthCheck,
+		Failover:           cfg.Failover,
+		Retry:              cfg.Retry,
+		BatchSize:          cfg.BatchSize,
+		ChainID:            cfg.ChainID,
+		ContractAddress:    cfg.ContractAddress,
+		ContractMethod:     cfg.ContractMethod,
+		PrivateKey:         cfg.PrivateKey,
+		DisableKeepAlives:  cfg.DisableKeepAlives,
+		DisableCompression: cfg.DisableCompression,
+		DisableRedirects:   cfg.DisableRedirects,
+		H2:                 cfg.H2,
+		ProxyAddr:          cfg.Proxy,
+		MaxIdleConns:       cfg.MaxIdleConns,
+		MaxConnsPerHost:    cfg.MaxConnsPerHost,
+		TLSConfig:          cfg.TLSConfig,
+		Verify:             cfg.Verify,
+		VerifyURL:          cfg.VerifyURL,
+		VerifyTimeout:      cfg.VerifyTimeout,
+		Audit:              cfg.Audit,
+		AuditQPS:           cfg.AuditQPS,
+		AuditWriter:        cfg.AuditWriter,
+		OnResult:           cfg.OnResult,
+		quiet:              true,
+	}
+	w.Run(ctx)
+	if err := w.Err(); err != nil {
+		return nil, err
+	}
+	report := w.Report()
+	return &report, nil
+}
+
+// publicResult converts res to a Result. startTime and start are the wall
+// clock and monotonic time of the start of the run.
+func publicResult(res *result, startTime time.Time, start time.Duration) Result {
+	return Result{
+		Op:         res.op,
+		Node:       res.node,
+		FailedOver: res.failedOver,
+		Attempts:   res.attempts,
+		Start:      startTime.Add(res.start - start),
+		Duration:   res.duration,
+		Warmup:     res.warmup,
+		Cancelled:  res.cancelled,
+		Err:        res.err,
+		Category:   res.category,
+		Account:    res.account,
+		Nonce:      res.nonce,
+		TxHash:     res.txHash,
+		Height:     res.height,
+	}
+}
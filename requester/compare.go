@@ -0,0 +1,249 @@
+package requester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// Verdicts of a comparison between two runs.
+const (
+	VerdictImproved  = "improved"
+	VerdictRegressed = "regressed"
+	VerdictNoise     = "noise"
+)
+
+// Name of the comparison of the overall results of two runs.
+const allOps = "all"
+
+// Percentiles compared between two runs.
+var comparePctls = []float64{50, 90, 99, 99.9}
+
+// CompareOptions holds the thresholds beyond which a difference between two
+// runs is considered an improvement or a regression.
+type CompareOptions struct {
+	// RpsThreshold is the change in throughput, in percent.
+	RpsThreshold float64
+
+	// LatencyThreshold is the change in average latency and latency
+	// percentiles, in percent.
+	LatencyThreshold float64
+
+	// ErrorThreshold is the change in error rate, in percentage points.
+	ErrorThreshold float64
+
+	// Alpha is the significance level of the t-test on the difference of
+	// the average latencies.
+	Alpha float64
+}
+
+// MetricComparison is the difference of one metric between two runs.
+type MetricComparison struct {
+	Name string
+	Base float64
+	Head float64
+
+	// Delta is the relative change from Base to Head in percent, or the
+	// absolute change in percentage points for error rates.
+	Delta float64
+
+	// PValue of the t-test, for the average latency only.
+	PValue float64
+
+	Verdict string
+}
+
+// OpComparison is the difference of the results of one operation.
+type OpComparison struct {
+	Op      string
+	Metrics []MetricComparison
+	Verdict string
+}
+
+// Comparison is the difference between a base run and a new run.
+type Comparison struct {
+	Ops     []OpComparison
+	Verdict string
+}
+
+// LoadJSONReport reads a report written by the "json" output.
+func LoadJSONReport(path string) (*JSONReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var doc JSONReport
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if doc.Schema != JSONSchema {
+		return nil, fmt.Errorf("%s: not a loombench report", path)
+	}
+	if doc.SchemaVersion != JSONSchemaVersion {
+		return nil, fmt.Errorf("%s: unsupported schema version %d", path, doc.SchemaVersion)
+	}
+	return &doc, nil
+}
+
+// Compare compares the results of head against base. Operations that don't
+// appear in both runs are skipped.
+func Compare(base, head *JSONReport, opts CompareOptions) *Comparison {
+	c := &Comparison{}
+	c.add(opts, allOps, summaryOp(base.Report), summaryOp(head.Report))
+
+	var names []string
+	for name := range head.Report.Ops {
+		if _, ok := base.Report.Ops[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.add(opts, name, base.Report.Ops[name], head.Report.Ops[name])
+	}
+
+	c.Verdict = VerdictNoise
+	for _, op := range c.Ops {
+		c.Verdict = combineVerdicts(c.Verdict, op.Verdict)
+	}
+	return c
+}
+
+// summaryOp returns the overall results of a report as an OpReport.
+func summaryOp(r Report) OpReport {
+	var errors int64
+	for _, n := range r.ErrorDist {
+		errors += int64(n)
+	}
+	return OpReport{
+		NumRes:              r.NumRes,
+		Errors:              errors,
+		Rps:                 r.Rps,
+		Average:             r.Average,
+		Fastest:             r.Fastest,
+		Slowest:             r.Slowest,
+		StdDev:              r.StdDev,
+		LatencyDistribution: r.LatencyDistribution,
+	}
+}
+
+func (c *Comparison) add(opts CompareOptions, name string, base, head OpReport) {
+	op := OpComparison{Op: name}
+
+	rps := MetricComparison{Name: "rps", Base: base.Rps, Head: head.Rps, PValue: -1}
+	rps.Delta = relativeChange(base.Rps, head.Rps)
+	rps.Verdict = thresholdVerdict(-rps.Delta, opts.RpsThreshold)
+	op.Metrics = append(op.Metrics, rps)
+
+	errRate := MetricComparison{Name: "error-rate", Base: errorRate(base), Head: errorRate(head), PValue: -1}
+	errRate.Delta = (errRate.Head - errRate.Base) * 100
+	errRate.Verdict = thresholdVerdict(errRate.Delta, opts.ErrorThreshold)
+	op.Metrics = append(op.Metrics, errRate)
+
+	avg := MetricComparison{Name: "average", Base: base.Average, Head: head.Average}
+	avg.Delta = relativeChange(base.Average, head.Average)
+	avg.PValue = welchTTest(base.NumRes-base.Errors, base.Average, base.StdDev,
+		head.NumRes-head.Errors, head.Average, head.StdDev)
+	avg.Verdict = thresholdVerdict(avg.Delta, opts.LatencyThreshold)
+	if avg.PValue >= opts.Alpha {
+		avg.Verdict = VerdictNoise
+	}
+	op.Metrics = append(op.Metrics, avg)
+
+	for _, p := range comparePctls {
+		b, ok1 := percentile(base.LatencyDistribution, p)
+		h, ok2 := percentile(head.LatencyDistribution, p)
+		if !ok1 || !ok2 {
+			continue
+		}
+		m := MetricComparison{Name: fmt.Sprintf("p%v", p), Base: b, Head: h, PValue: -1}
+		m.Delta = relativeChange(b, h)
+		m.Verdict = thresholdVerdict(m.Delta, opts.LatencyThreshold)
+		op.Metrics = append(op.Metrics, m)
+	}
+
+	op.Verdict = VerdictNoise
+	for _, m := range op.Metrics {
+		op.Verdict = combineVerdicts(op.Verdict, m.Verdict)
+	}
+	c.Ops = append(c.Ops, op)
+}
+
+// Print writes the comparison as a table.
+func (c *Comparison) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, op := range c.Ops {
+		fmt.Fprintf(tw, "%s:\n", op.Op)
+		fmt.Fprintf(tw, "  metric\tbase\tnew\tdelta\tverdict\n")
+		for _, m := range op.Metrics {
+			delta := fmt.Sprintf("%+.2f%%", m.Delta)
+			if m.Name == "error-rate" {
+				delta = fmt.Sprintf("%+.2fpp", m.Delta)
+			}
+			verdict := m.Verdict
+			if m.PValue >= 0 {
+				verdict = fmt.Sprintf("%s (p=%.3f)", verdict, m.PValue)
+			}
+			fmt.Fprintf(tw, "  %s\t%4.4f\t%4.4f\t%s\t%s\n", m.Name, m.Base, m.Head, delta, verdict)
+		}
+		fmt.Fprintf(tw, "  verdict:\t%s\n\n", op.Verdict)
+	}
+	fmt.Fprintf(tw, "Verdict: %s\n", c.Verdict)
+	tw.Flush()
+}
+
+func percentile(dist []LatencyDistribution, p float64) (float64, bool) {
+	for _, d := range dist {
+		if d.Percentage == p {
+			return d.Latency, true
+		}
+	}
+	return 0, false
+}
+
+func errorRate(op OpReport) float64 {
+	if op.NumRes == 0 {
+		return 0
+	}
+	return float64(op.Errors) / float64(op.NumRes)
+}
+
+// relativeChange returns the change from base to head in percent.
+func relativeChange(base, head float64) float64 {
+	if base == 0 {
+		if head == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (head - base) / base * 100
+}
+
+// thresholdVerdict returns the verdict for a change where positive values
+// are worse.
+func thresholdVerdict(worse, threshold float64) string {
+	switch {
+	case worse > threshold:
+		return VerdictRegressed
+	case worse < -threshold:
+		return VerdictImproved
+	}
+	return VerdictNoise
+}
+
+// combineVerdicts returns the overall verdict of two verdicts. Any
+// regression is a regression, otherwise any improvement is an improvement.
+func combineVerdicts(a, b string) string {
+	if a == VerdictRegressed || b == VerdictRegressed {
+		return VerdictRegressed
+	}
+	if a == VerdictImproved || b == VerdictImproved {
+		return VerdictImproved
+	}
+	return VerdictNoise
+}
@@ -0,0 +1,202 @@
+package requester
+
+import (
+	"math"
+	"testing"
+)
+
+var testCompareOptions = CompareOptions{
+	RpsThreshold:     5,
+	LatencyThreshold: 10,
+	ErrorThreshold:   0.1,
+	Alpha:            0.05,
+}
+
+// testRun returns a report of 10000 requests with the given throughput,
+// errors and latencies in seconds.
+func testRun(rps float64, errors int, avg, sd, p99 float64) *JSONReport {
+	r := Report{
+		NumRes:    10000,
+		Rps:       rps,
+		Average:   avg,
+		StdDev:    sd,
+		ErrorDist: map[string]int{},
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: avg},
+			{Percentage: 99, Latency: p99},
+		},
+	}
+	if errors > 0 {
+		r.ErrorDist["timeout"] = errors
+	}
+	return &JSONReport{Schema: JSONSchema, SchemaVersion: JSONSchemaVersion, Report: r}
+}
+
+func TestCompare(t *testing.T) {
+	base := testRun(1000, 0, 0.100, 0.020, 0.200)
+	tests := []struct {
+		name    string
+		head    *JSONReport
+		verdict string
+		metrics map[string]string
+	}{
+		{
+			name:    "same",
+			head:    testRun(1000, 0, 0.100, 0.020, 0.200),
+			verdict: VerdictNoise,
+			metrics: map[string]string{"rps": VerdictNoise, "average": VerdictNoise, "p99": VerdictNoise},
+		},
+		{
+			name:    "within thresholds",
+			head:    testRun(970, 5, 0.105, 0.020, 0.215),
+			verdict: VerdictNoise,
+			metrics: map[string]string{"rps": VerdictNoise, "error-rate": VerdictNoise, "p99": VerdictNoise},
+		},
+		{
+			name:    "lower rps",
+			head:    testRun(900, 0, 0.100, 0.020, 0.200),
+			verdict: VerdictRegressed,
+			metrics: map[string]string{"rps": VerdictRegressed, "p99": VerdictNoise},
+		},
+		{
+			name:    "slower tail",
+			head:    testRun(1000, 0, 0.100, 0.020, 0.300),
+			verdict: VerdictRegressed,
+			metrics: map[string]string{"rps": VerdictNoise, "p50": VerdictNoise, "p99": VerdictRegressed},
+		},
+		{
+			name:    "slower average",
+			head:    testRun(1000, 0, 0.120, 0.020, 0.200),
+			verdict: VerdictRegressed,
+			metrics: map[string]string{"average": VerdictRegressed, "p50": VerdictRegressed},
+		},
+		{
+			name:    "more errors",
+			head:    testRun(1000, 50, 0.100, 0.020, 0.200),
+			verdict: VerdictRegressed,
+			metrics: map[string]string{"error-rate": VerdictRegressed},
+		},
+		{
+			name:    "faster",
+			head:    testRun(1200, 0, 0.080, 0.020, 0.150),
+			verdict: VerdictImproved,
+			metrics: map[string]string{"rps": VerdictImproved, "average": VerdictImproved, "p99": VerdictImproved},
+		},
+		{
+			name:    "faster but fewer",
+			head:    testRun(900, 0, 0.080, 0.020, 0.150),
+			verdict: VerdictRegressed,
+			metrics: map[string]string{"rps": VerdictRegressed, "average": VerdictImproved},
+		},
+	}
+	for _, tt := range tests {
+		c := Compare(base, tt.head, testCompareOptions)
+		if c.Verdict != tt.verdict {
+			t.Errorf("%s: verdict = %s, want %s", tt.name, c.Verdict, tt.verdict)
+		}
+		if len(c.Ops) != 1 || c.Ops[0].Op != allOps {
+			t.Errorf("%s: got %d ops, want only %q", tt.name, len(c.Ops), allOps)
+			continue
+		}
+		for _, m := range c.Ops[0].Metrics {
+			if want, ok := tt.metrics[m.Name]; ok && m.Verdict != want {
+				t.Errorf("%s: %s verdict = %s (delta %.2f), want %s", tt.name, m.Name, m.Verdict, m.Delta, want)
+			}
+		}
+	}
+}
+
+func TestCompareNoisyAverage(t *testing.T) {
+	// A 20% slower average over few, widely spread requests isn't
+	// significant.
+	base := testRun(1000, 0, 0.100, 0.100, 0.200)
+	head := testRun(1000, 0, 0.120, 0.100, 0.200)
+	base.Report.NumRes, head.Report.NumRes = 10, 10
+
+	c := Compare(base, head, testCompareOptions)
+	for _, m := range c.Ops[0].Metrics {
+		if m.Name != "average" {
+			continue
+		}
+		if m.PValue < testCompareOptions.Alpha {
+			t.Errorf("p-value = %v, want at least %v", m.PValue, testCompareOptions.Alpha)
+		}
+		if m.Verdict != VerdictNoise {
+			t.Errorf("verdict = %s, want %s", m.Verdict, VerdictNoise)
+		}
+	}
+}
+
+func TestCompareOps(t *testing.T) {
+	base := testRun(1000, 0, 0.100, 0.020, 0.200)
+	base.Report.Ops = map[string]OpReport{
+		"read":  {NumRes: 5000, Rps: 500, Average: 0.050, StdDev: 0.010},
+		"write": {NumRes: 5000, Rps: 500, Average: 0.150, StdDev: 0.010},
+	}
+	head := testRun(1000, 0, 0.100, 0.020, 0.200)
+	head.Report.Ops = map[string]OpReport{
+		"write": {NumRes: 5000, Rps: 400, Average: 0.150, StdDev: 0.010},
+		"call":  {NumRes: 5000, Rps: 500, Average: 0.150, StdDev: 0.010},
+	}
+
+	c := Compare(base, head, testCompareOptions)
+	if len(c.Ops) != 2 || c.Ops[1].Op != "write" {
+		t.Fatalf("got ops %v, want all and write", c.Ops)
+	}
+	if c.Ops[0].Verdict != VerdictNoise || c.Ops[1].Verdict != VerdictRegressed {
+		t.Errorf("verdicts = %s, %s, want %s, %s", c.Ops[0].Verdict, c.Ops[1].Verdict, VerdictNoise, VerdictRegressed)
+	}
+	if c.Verdict != VerdictRegressed {
+		t.Errorf("verdict = %s, want %s", c.Verdict, VerdictRegressed)
+	}
+}
+
+func TestRegIncBeta(t *testing.T) {
+	tests := []struct {
+		a, b, x float64
+		want    float64
+	}{
+		{1, 1, 0.3, 0.3},
+		{2, 3, 0.5, 0.6875},
+		{3, 1, 0.4, 0.064},        // x^a
+		{1, 4, 0.2, 0.59040},      // 1 - (1-x)^b
+		{10, 10, 0.5, 0.5},        // symmetric
+		{0.5, 0.5, 0.5, 0.5},      // symmetric
+		{5, 0.5, 0, 0},            // lower bound
+		{5, 0.5, 1, 1},            // upper bound
+		{50, 0.5, 0.9, 0.0012041}, // t distribution, far into the tail
+	}
+	for _, tt := range tests {
+		if got := regIncBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("regIncBeta(%v, %v, %v) = %v, want %v", tt.a, tt.b, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		n1         int64
+		mean1, sd1 float64
+		n2         int64
+		mean2, sd2 float64
+		want       float64
+	}{
+		// t = 1 with 2 degrees of freedom: 1 - 1/sqrt(3).
+		{"df 2", 2, 1, 1, 2, 0, 1, 1 - 1/math.Sqrt(3)},
+		// t = 2 with 2 degrees of freedom: 1 - 2/sqrt(6).
+		{"df 2 t 2", 2, 2, 1, 2, 0, 1, 1 - 2/math.Sqrt(6)},
+		// t = 2.228 with 10 degrees of freedom is the 5% critical value.
+		{"critical", 6, 2.228138852, math.Sqrt(3), 6, 0, math.Sqrt(3), 0.05},
+		{"equal", 100, 1, 0.5, 100, 1, 0.5, 1},
+		{"too few", 1, 1, 1, 100, 2, 1, 1},
+		{"no variance", 10, 1, 0, 10, 1, 0, 1},
+		{"no variance, different", 10, 1, 0, 10, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		got := welchTTest(tt.n1, tt.mean1, tt.sd1, tt.n2, tt.mean2, tt.sd2)
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: welchTTest() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package requester
+
+import "time"
+
+// processCPU reports that the CPU time of the process isn't available.
+func processCPU() (user, system time.Duration, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,25 @@
+package requester
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPU returns the user and system CPU time used by the process.
+func processCPU() (user, system time.Duration, ok bool) {
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, 0, false
+	}
+	var creation, exit, kernel, usr syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &usr); err != nil {
+		return 0, 0, false
+	}
+	return filetimeDuration(usr), filetimeDuration(kernel), true
+}
+
+// filetimeDuration returns the duration of ft, which counts 100ns
+// intervals.
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(int64(ft.HighDateTime)<<32|int64(ft.LowDateTime)) * 100
+}
@@ -0,0 +1,114 @@
+package requester
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// Events of a node during a run.
+const (
+	NodeEjected    = "ejected"    // the node failed its health checks
+	NodeReadmitted = "readmitted" // the node passed a health check again
+)
+
+// NodeEvent is a change in the health of a node during a run.
+type NodeEvent struct {
+	Time  time.Time `json:"time"`
+	Node  string    `json:"node"`
+	Event string    `json:"event"`
+
+	// Error is the error of the last health check of an ejected node.
+	Error string `json:"error,omitempty"`
+}
+
+// nodeEvents records the node events of a run.
+type nodeEvents struct {
+	mu     sync.Mutex
+	events []NodeEvent
+}
+
+func (e *nodeEvents) add(ev NodeEvent) {
+	e.mu.Lock()
+	e.events = append(e.events, ev)
+	e.mu.Unlock()
+}
+
+func (e *nodeEvents) list() []NodeEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]NodeEvent(nil), e.events...)
+}
+
+// startHealthCheck starts checking the health of the nodes every
+// HealthCheck until ctx is done, recording their ejections and
+// readmissions.
+func (b *Work) startHealthCheck(ctx context.Context) {
+	var targets []loomclient.HealthTarget
+	for _, n := range b.nodes() {
+		targets = append(targets, loomclient.HealthTarget{Name: n.Name, WriteURI: n.WriteURL, ReadURI: n.ReadURL})
+	}
+	timeout := time.Duration(b.Timeout) * time.Second
+	if timeout <= 0 || timeout > b.HealthCheck {
+		timeout = b.HealthCheck
+	}
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: b.TLSConfig},
+		Timeout:   timeout,
+	}
+	b.health = loomclient.NewHealthChecker(client, targets...)
+	b.health.Interval = b.HealthCheck
+	b.health.OnChange = func(name string, healthy bool, err error) {
+		ev := NodeEvent{Time: time.Now(), Node: name, Event: NodeReadmitted}
+		if !healthy {
+			ev.Event = NodeEjected
+			if err != nil {
+				ev.Error = err.Error()
+			}
+		}
+		b.events.add(ev)
+	}
+	go b.health.Run(ctx)
+}
+
+// healthy reports whether n is in rotation.
+func (b *Work) healthy(n *workerNode) bool {
+	return b.health == nil || b.health.Healthy(n.name)
+}
+
+// healthyNode returns nodes[i], or the next healthy node after it if it
+// was ejected. If every node was ejected, it returns nodes[i].
+func (b *Work) healthyNode(nodes []*workerNode, i int) *workerNode {
+	for j := 0; j < len(nodes); j++ {
+		if n := nodes[(i+j)%len(nodes)]; b.healthy(n) {
+			return n
+		}
+	}
+	return nodes[i]
+}
+
+// failoverNode returns the healthy node after the current node of ws to
+// retry its failed request on, or nil if there is none.
+func (b *Work) failoverNode(ws *WorkerState) *workerNode {
+	for i, n := range ws.nodes {
+		if n != ws.node {
+			continue
+		}
+		for j := 1; j < len(ws.nodes); j++ {
+			if m := ws.nodes[(i+j)%len(ws.nodes)]; b.healthy(m) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// canFailover reports whether a request that failed with an error of
+// category can be retried on another node: only requests that didn't reach
+// the node, or got no reply from it in time, are.
+func canFailover(category string) bool {
+	return category == loomclient.ErrCategoryTransport || category == loomclient.ErrCategoryTimeout
+}
@@ -0,0 +1,262 @@
+package requester
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Latencies are recorded in microseconds, with 3 significant digits of
+// precision, up to an hour. Larger values are clamped to an hour.
+const (
+	hdrUnit         = time.Microsecond
+	hdrHighest      = int64(time.Hour / hdrUnit)
+	hdrSignificance = 3
+)
+
+// hdrHistogram is a High Dynamic Range histogram of durations. It counts every
+// recorded value in constant memory while keeping each value to within
+// 0.1% precision, so percentiles stay accurate far into the tail.
+//
+// The layout follows the HdrHistogram algorithm: values are grouped into
+// buckets of powers of two, each split into a fixed number of linear
+// sub-buckets.
+type hdrHistogram struct {
+	subBucketHalfCountMagnitude uint
+	subBucketHalfCount          int
+	subBucketMask               int64
+	subBucketCount              int
+
+	counts     []int64
+	totalCount int64
+
+	// Exact extremes and sums, kept alongside the buckets.
+	min   time.Duration
+	max   time.Duration
+	sum   float64 // seconds
+	sumSq float64 // seconds squared
+}
+
+func newHDRHistogram() *hdrHistogram {
+	largestSingleUnit := 2 * int64(math.Pow10(hdrSignificance))
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(float64(largestSingleUnit))))
+	subBucketHalfCountMagnitude := subBucketCountMagnitude - 1
+	subBucketCount := 1 << subBucketCountMagnitude
+
+	smallestUntrackable := int64(subBucketCount)
+	bucketCount := 1
+	for smallestUntrackable <= hdrHighest {
+		smallestUntrackable <<= 1
+		bucketCount++
+	}
+
+	return &hdrHistogram{
+		subBucketHalfCountMagnitude: subBucketHalfCountMagnitude,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketMask:               int64(subBucketCount - 1),
+		subBucketCount:              subBucketCount,
+		counts:                      make([]int64, (bucketCount+1)*(subBucketCount/2)),
+	}
+}
+
+// record adds d to the histogram.
+func (h *hdrHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	v := int64(d / hdrUnit)
+	if v > hdrHighest {
+		v = hdrHighest
+	}
+	h.counts[h.countsIndexFor(v)]++
+	if h.totalCount == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.totalCount++
+	h.sum += d.Seconds()
+	h.sumSq += d.Seconds() * d.Seconds()
+}
+
+// merge adds all values recorded in o to h.
+func (h *hdrHistogram) merge(o *hdrHistogram) {
+	if o.totalCount == 0 {
+		return
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.totalCount == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.totalCount += o.totalCount
+	h.sum += o.sum
+	h.sumSq += o.sumSq
+}
+
+func (h *hdrHistogram) reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.totalCount = 0
+	h.min = 0
+	h.max = 0
+	h.sum = 0
+	h.sumSq = 0
+}
+
+func (h *hdrHistogram) count() int64 {
+	return h.totalCount
+}
+
+// mean returns the exact mean of the recorded values in seconds.
+func (h *hdrHistogram) mean() float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	return h.sum / float64(h.totalCount)
+}
+
+// stdDev returns the standard deviation of the recorded values in seconds.
+func (h *hdrHistogram) stdDev() float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	mean := h.mean()
+	variance := h.sumSq/float64(h.totalCount) - mean*mean
+	if variance < 0 {
+		return 0
+	}
+	return math.Sqrt(variance)
+}
+
+// quantile returns the value in seconds below which q percent of the
+// recorded values fall.
+func (h *hdrHistogram) quantile(q float64) float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	if q > 100 {
+		q = 100
+	}
+	countAtPercentile := int64(q/100*float64(h.totalCount) + 0.5)
+	if countAtPercentile < 1 {
+		countAtPercentile = 1
+	}
+
+	var total int64
+	for i, c := range h.counts {
+		total += c
+		if total >= countAtPercentile {
+			v := time.Duration(h.highestEquivalentValue(h.valueFromCountsIndex(i))) * hdrUnit
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v.Seconds()
+		}
+	}
+	return h.max.Seconds()
+}
+
+// distribution returns the latency at each of pctls.
+func (h *hdrHistogram) distribution(pctls []float64) []LatencyDistribution {
+	res := make([]LatencyDistribution, 0, len(pctls))
+	if h.totalCount == 0 {
+		return res
+	}
+	for _, p := range pctls {
+		res = append(res, LatencyDistribution{Percentage: p, Latency: h.quantile(p)})
+	}
+	return res
+}
+
+// buckets splits the range between the fastest and slowest values into bc
+// linear buckets, counting the values that fall at or below each mark.
+func (h *hdrHistogram) buckets(bc int) []Bucket {
+	if h.totalCount == 0 {
+		return nil
+	}
+	fastest, slowest := h.min.Seconds(), h.max.Seconds()
+	marks := make([]float64, bc+1)
+	counts := make([]int, bc+1)
+	bs := (slowest - fastest) / float64(bc)
+	for i := 0; i < bc; i++ {
+		marks[i] = fastest + bs*float64(i)
+	}
+	marks[bc] = slowest
+
+	bi := 0
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		v := time.Duration(h.valueFromCountsIndex(i)) * hdrUnit
+		if v < h.min {
+			v = h.min
+		}
+		secs := v.Seconds()
+		for bi < bc && secs > marks[bi] {
+			bi++
+		}
+		counts[bi] += int(c)
+	}
+
+	res := make([]Bucket, len(marks))
+	for i := range marks {
+		res[i] = Bucket{
+			Mark:      marks[i],
+			Count:     counts[i],
+			Frequency: float64(counts[i]) / float64(h.totalCount),
+		}
+	}
+	return res
+}
+
+func (h *hdrHistogram) bucketIndex(v int64) int {
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(v|h.subBucketMask))
+	return pow2Ceiling - int(h.subBucketHalfCountMagnitude+1)
+}
+
+func (h *hdrHistogram) subBucketIndex(v int64, bucketIdx int) int {
+	return int(v >> uint(bucketIdx))
+}
+
+func (h *hdrHistogram) countsIndex(bucketIdx, subBucketIdx int) int {
+	bucketBaseIdx := (bucketIdx + 1) << h.subBucketHalfCountMagnitude
+	return bucketBaseIdx + subBucketIdx - h.subBucketHalfCount
+}
+
+func (h *hdrHistogram) countsIndexFor(v int64) int {
+	bucketIdx := h.bucketIndex(v)
+	return h.countsIndex(bucketIdx, h.subBucketIndex(v, bucketIdx))
+}
+
+func (h *hdrHistogram) valueFromCountsIndex(i int) int64 {
+	bucketIdx := (i >> h.subBucketHalfCountMagnitude) - 1
+	subBucketIdx := (i & (h.subBucketHalfCount - 1)) + h.subBucketHalfCount
+	if bucketIdx < 0 {
+		subBucketIdx -= h.subBucketHalfCount
+		bucketIdx = 0
+	}
+	return int64(subBucketIdx) << uint(bucketIdx)
+}
+
+func (h *hdrHistogram) sizeOfEquivalentValueRange(v int64) int64 {
+	bucketIdx := h.bucketIndex(v)
+	if h.subBucketIndex(v, bucketIdx) >= h.subBucketCount {
+		bucketIdx++
+	}
+	return 1 << uint(bucketIdx)
+}
+
+func (h *hdrHistogram) highestEquivalentValue(v int64) int64 {
+	return v + h.sizeOfEquivalentValueRange(v) - 1
+}
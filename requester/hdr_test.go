@@ -0,0 +1,197 @@
+package requester
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// within reports whether got is within 0.1% of want, the precision of the
+// histogram.
+func within(got, want float64) bool {
+	return math.Abs(got-want) <= want/1000
+}
+
+func TestHDRQuantile(t *testing.T) {
+	h := newHDRHistogram()
+	for i := 1; i <= 100000; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Microsecond},
+		{10, 10 * time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{99.9, 99900 * time.Microsecond},
+		{99.99, 99990 * time.Microsecond},
+		{99.999, 99999 * time.Microsecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := h.quantile(tt.q); !within(got, tt.want.Seconds()) {
+			t.Errorf("quantile(%v) = %v, want %v", tt.q, got, tt.want.Seconds())
+		}
+	}
+
+	if got, want := h.count(), int64(100000); got != want {
+		t.Errorf("count() = %d, want %d", got, want)
+	}
+	if got, want := h.min, time.Microsecond; got != want {
+		t.Errorf("min = %v, want %v", got, want)
+	}
+	if got, want := h.max, 100*time.Millisecond; got != want {
+		t.Errorf("max = %v, want %v", got, want)
+	}
+	if got, want := h.mean(), 0.0500005; !within(got, want) {
+		t.Errorf("mean() = %v, want %v", got, want)
+	}
+}
+
+func TestHDRQuantileEmpty(t *testing.T) {
+	h := newHDRHistogram()
+	if got := h.quantile(99); got != 0 {
+		t.Errorf("quantile(99) = %v, want 0", got)
+	}
+	if got := h.distribution([]float64{50, 99}); len(got) != 0 {
+		t.Errorf("distribution() = %v, want none", got)
+	}
+	if got := h.buckets(10); got != nil {
+		t.Errorf("buckets() = %v, want nil", got)
+	}
+}
+
+func TestHDRMerge(t *testing.T) {
+	all, odd, even := newHDRHistogram(), newHDRHistogram(), newHDRHistogram()
+	for i := 1; i <= 10000; i++ {
+		d := time.Duration(i) * 37 * time.Microsecond
+		all.record(d)
+		if i%2 == 0 {
+			even.record(d)
+		} else {
+			odd.record(d)
+		}
+	}
+
+	merged := newHDRHistogram()
+	merged.merge(newHDRHistogram())
+	merged.merge(odd)
+	merged.merge(even)
+
+	if merged.count() != all.count() {
+		t.Errorf("count() = %d, want %d", merged.count(), all.count())
+	}
+	if merged.min != all.min || merged.max != all.max {
+		t.Errorf("min, max = %v, %v, want %v, %v", merged.min, merged.max, all.min, all.max)
+	}
+	if !within(merged.mean(), all.mean()) {
+		t.Errorf("mean() = %v, want %v", merged.mean(), all.mean())
+	}
+	for _, q := range []float64{10, 50, 75, 90, 99, 99.9, 99.99, 100} {
+		if got, want := merged.quantile(q), all.quantile(q); got != want {
+			t.Errorf("quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+
+	merged.reset()
+	if merged.count() != 0 || merged.quantile(50) != 0 || merged.max != 0 {
+		t.Errorf("reset() left count %d, max %v", merged.count(), merged.max)
+	}
+}
+
+func TestHDRBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []time.Duration
+		bc     int
+		want   []int
+	}{
+		{
+			name:   "single",
+			values: []time.Duration{time.Millisecond},
+			bc:     2,
+			want:   []int{1, 0, 0},
+		},
+		{
+			name: "linear",
+			values: []time.Duration{
+				10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond,
+				40 * time.Millisecond, 50 * time.Millisecond,
+			},
+			bc:   4,
+			want: []int{1, 1, 1, 1, 1},
+		},
+		{
+			name: "skewed",
+			values: []time.Duration{
+				time.Millisecond, time.Millisecond, time.Millisecond,
+				2 * time.Millisecond, 100 * time.Millisecond,
+			},
+			bc:   2,
+			want: []int{3, 1, 1},
+		},
+	}
+	for _, tt := range tests {
+		h := newHDRHistogram()
+		for _, v := range tt.values {
+			h.record(v)
+		}
+		got := h.buckets(tt.bc)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d buckets, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i, b := range got {
+			if b.Count != tt.want[i] {
+				t.Errorf("%s: bucket %d count = %d, want %d", tt.name, i, b.Count, tt.want[i])
+			}
+			if want := float64(tt.want[i]) / float64(len(tt.values)); b.Frequency != want {
+				t.Errorf("%s: bucket %d frequency = %v, want %v", tt.name, i, b.Frequency, want)
+			}
+		}
+		if got[0].Mark != h.min.Seconds() || got[len(got)-1].Mark != h.max.Seconds() {
+			t.Errorf("%s: marks span %v-%v, want %v-%v", tt.name,
+				got[0].Mark, got[len(got)-1].Mark, h.min.Seconds(), h.max.Seconds())
+		}
+	}
+}
+
+func TestHDRHighest(t *testing.T) {
+	tests := []struct {
+		name  string
+		value time.Duration
+		want  time.Duration // p100
+	}{
+		{"below", time.Hour - time.Second, time.Hour - time.Second},
+		{"at", time.Hour, time.Hour},
+		{"above", 2 * time.Hour, time.Hour},
+		{"far above", 1000 * time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		h := newHDRHistogram()
+		h.record(time.Millisecond)
+		h.record(tt.value)
+		if h.max != tt.value {
+			t.Errorf("%s: max = %v, want %v", tt.name, h.max, tt.value)
+		}
+		if got := h.quantile(100); !within(got, tt.want.Seconds()) {
+			t.Errorf("%s: quantile(100) = %v, want %v", tt.name, got, tt.want.Seconds())
+		}
+		if got := h.quantile(50); !within(got, time.Millisecond.Seconds()) {
+			t.Errorf("%s: quantile(50) = %v, want %v", tt.name, got, time.Millisecond.Seconds())
+		}
+	}
+}
+
+func TestHDRNegative(t *testing.T) {
+	h := newHDRHistogram()
+	h.record(-time.Second)
+	if h.count() != 1 || h.min != 0 || h.quantile(100) != 0 {
+		t.Errorf("record(-1s) = count %d, min %v, p100 %v, want 1, 0, 0",
+			h.count(), h.min, h.quantile(100))
+	}
+}
@@ -0,0 +1,543 @@
+package requester
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Dimensions of the charts of the html output, in pixels.
+const (
+	chartWidth  = 720
+	chartHeight = 260
+	chartLeft   = 64
+	chartRight  = 16
+	chartTop    = 28
+	chartBottom = 40
+
+	// Minimum space between two labels of the x axis.
+	chartLabelGap = 36
+)
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd"}
+
+type point struct {
+	x, y float64
+}
+
+type series struct {
+	name   string
+	points []point
+}
+
+type tick struct {
+	v     float64
+	label string
+}
+
+type configRow struct {
+	Name  string
+	Value interface{}
+}
+
+type errorRow struct {
+	Count   int
+	Percent float64
+	Error   string
+}
+
+type opSection struct {
+	Name string
+	OpReport
+	Histogram template.HTML
+}
+
+// htmlReport is the data of the html output. Charts are rendered to inline
+// SVG ahead of time so the page has no external dependencies.
+type htmlReport struct {
+	Run    RunInfo
+	Report Report
+	Info   []configRow
+	Config []configRow
+	Errors []errorRow
+
+	LatencyChart    template.HTML
+	ThroughputChart template.HTML
+	PercentileChart template.HTML
+	Histogram       template.HTML
+	Ops             []opSection
+}
+
+func (r *report) printHTML() error {
+	snapshot := r.snapshot()
+	doc := htmlReport{
+		Run:             r.info,
+		Report:          snapshot,
+		Info:            infoRows(r.info),
+		Config:          configRows(r.info.Config),
+		Errors:          errorRows(snapshot),
+		LatencyChart:    latencyChart(snapshot.Intervals),
+		ThroughputChart: throughputChart(snapshot.Intervals),
+		PercentileChart: percentileChart(snapshot.LatencyDistribution),
+		Histogram:       barChart(snapshot.Histogram),
+	}
+
+	var names []string
+	for name := range snapshot.Ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op := snapshot.Ops[name]
+		doc.Ops = append(doc.Ops, opSection{Name: name, OpReport: op, Histogram: barChart(op.Histogram)})
+	}
+	return htmlTmpl.Execute(r.w, doc)
+}
+
+// infoRows describes the run and the nodes it ran against.
+func infoRows(info RunInfo) []configRow {
+	rows := []configRow{
+		{"loombench version", info.Version},
+		{"loombench commit", info.GitCommit},
+		{"Start time", info.StartTime.Format("2006-01-02 15:04:05 MST")},
+		{"End time", info.EndTime.Format("2006-01-02 15:04:05 MST")},
+		{"Chain ID", info.ChainID},
+		{"Contract address", info.ContractAddress},
+		{"Contract method", info.ContractMethod},
+		{"Write URL", info.WriteURL},
+		{"Read URL", info.ReadURL},
+	}
+	if n := info.Node; n != nil {
+		rows = append(rows,
+			configRow{"Node moniker", n.Moniker},
+			configRow{"Node network", n.Network},
+			configRow{"Node version", n.Version},
+			configRow{"Node commit", n.GitCommit},
+			configRow{"Node height", n.Height},
+		)
+	}
+	for _, n := range info.Nodes {
+		rows = append(rows, configRow{"Node " + n.Name,
+			fmt.Sprintf("write %s, read %s, weight %v", n.WriteURL, n.ReadURL, n.weight())})
+	}
+	if info.Balance != "" {
+		rows = append(rows, configRow{"Balance", info.Balance})
+	}
+	return rows
+}
+
+// configRows lists every option of c by its name in the json output, so
+// that new options show up without changes here. Optional options that
+// weren't set are left out.
+func configRows(c RunConfig) []configRow {
+	return appendConfigRows(nil, "", reflect.ValueOf(c))
+}
+
+func appendConfigRows(rows []configRow, prefix string, v reflect.Value) []configRow {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")
+		name := prefix + tag[0]
+		if tag[0] == "" {
+			name = prefix + t.Field(i).Name
+		}
+		omitEmpty := len(tag) > 1 && tag[1] == "omitempty"
+
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if omitEmpty && f.IsZero() {
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Struct:
+			rows = appendConfigRows(rows, name+".", f)
+		case reflect.Slice:
+			var items []string
+			for j := 0; j < f.Len(); j++ {
+				items = append(items, fmt.Sprint(f.Index(j).Interface()))
+			}
+			rows = append(rows, configRow{name, strings.Join(items, ", ")})
+		default:
+			rows = append(rows, configRow{name, f.Interface()})
+		}
+	}
+	return rows
+}
+
+func errorRows(r Report) []errorRow {
+	var rows []errorRow
+	for err, n := range r.ErrorDist {
+		row := errorRow{Count: n, Error: err}
+		if r.NumRes > 0 {
+			row.Percent = float64(n) / float64(r.NumRes) * 100
+		}
+		rows = append(rows, row)
+	}
+	sort.Sort(byCount(rows))
+	return rows
+}
+
+// byCount sorts errors by descending count.
+type byCount []errorRow
+
+func (s byCount) Len() int      { return len(s) }
+func (s byCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Error < s[j].Error
+}
+
+func latencyChart(intervals []Interval) template.HTML {
+	ss := []series{{name: "average"}, {name: "p50"}, {name: "p90"}, {name: "p99"}}
+	for _, iv := range intervals {
+		if iv.NumRes == iv.Errors {
+			continue
+		}
+		x := (iv.Start + iv.Duration).Seconds()
+		ss[0].points = append(ss[0].points, point{x, iv.Average})
+		ss[1].points = append(ss[1].points, point{x, iv.P50})
+		ss[2].points = append(ss[2].points, point{x, iv.P90})
+		ss[3].points = append(ss[3].points, point{x, iv.P99})
+	}
+	return lineChart("time (secs)", "latency (secs)", nil, ss)
+}
+
+func throughputChart(intervals []Interval) template.HTML {
+	ss := []series{{name: "requests/sec"}, {name: "errors/sec"}}
+	for _, iv := range intervals {
+		x := (iv.Start + iv.Duration).Seconds()
+		var errs float64
+		if iv.Duration > 0 {
+			errs = float64(iv.Errors) / iv.Duration.Seconds()
+		}
+		ss[0].points = append(ss[0].points, point{x, iv.Rps})
+		ss[1].points = append(ss[1].points, point{x, errs})
+	}
+	return lineChart("time (secs)", "requests/sec", nil, ss)
+}
+
+// percentileChart plots the latency distribution against the number of
+// nines of each percentile, so the tail isn't squashed against the edge.
+func percentileChart(dist []LatencyDistribution) template.HTML {
+	s := series{name: "latency"}
+	var ticks []tick
+	for _, d := range dist {
+		x := -math.Log10(1 - d.Percentage/100)
+		s.points = append(s.points, point{x, d.Latency})
+		ticks = append(ticks, tick{x, fmt.Sprintf("p%v", d.Percentage)})
+	}
+	return lineChart("percentile", "latency (secs)", ticks, []series{s})
+}
+
+// lineChart renders series as an inline SVG line chart. If xTicks is nil,
+// the x axis is labelled at even steps.
+func lineChart(xLabel, yLabel string, xTicks []tick, ss []series) template.HTML {
+	xmin, xmax := math.Inf(1), math.Inf(-1)
+	ymax := 0.0
+	for _, s := range ss {
+		for _, p := range s.points {
+			xmin = math.Min(xmin, p.x)
+			xmax = math.Max(xmax, p.x)
+			ymax = math.Max(ymax, p.y)
+		}
+	}
+	if math.IsInf(xmin, 1) {
+		return template.HTML(`<p class="empty">No data.</p>`)
+	}
+	if xmax == xmin {
+		xmax = xmin + 1
+	}
+	ymax = niceCeil(ymax)
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	px := func(x float64) float64 { return chartLeft + (x-xmin)/(xmax-xmin)*plotW }
+	py := func(y float64) float64 { return chartTop + plotH - y/ymax*plotH }
+
+	buf := &bytes.Buffer{}
+	startChart(buf, xLabel, yLabel, ymax)
+
+	if xTicks == nil {
+		for i := 0; i <= 5; i++ {
+			v := xmin + (xmax-xmin)*float64(i)/5
+			xTicks = append(xTicks, tick{v, fmt.Sprintf("%.3g", v)})
+		}
+	}
+	// Label from the right, where percentile ticks are sparse.
+	last := math.Inf(1)
+	for i := len(xTicks) - 1; i >= 0; i-- {
+		t := xTicks[i]
+		x := px(t.v)
+		if last-x < chartLabelGap {
+			continue
+		}
+		last = x
+		fmt.Fprintf(buf, `<line class="grid" x1="%.1f" y1="%d" x2="%.1f" y2="%.1f"/>`, x, chartTop, x, chartTop+plotH)
+		fmt.Fprintf(buf, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, x, chartTop+plotH+14, template.HTMLEscapeString(t.label))
+	}
+
+	for i, s := range ss {
+		color := chartColors[i%len(chartColors)]
+		var pts []string
+		for _, p := range s.points {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", px(p.x), py(p.y)))
+		}
+		fmt.Fprintf(buf, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(pts, " "))
+		if len(s.points) == 1 {
+			p := s.points[0]
+			fmt.Fprintf(buf, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`, px(p.x), py(p.y), color)
+		}
+		lx := chartLeft + 8 + i*110
+		fmt.Fprintf(buf, `<rect x="%d" y="8" width="10" height="10" fill="%s"/>`, lx, color)
+		fmt.Fprintf(buf, `<text x="%d" y="17">%s</text>`, lx+14, template.HTMLEscapeString(s.name))
+	}
+	buf.WriteString(`</svg>`)
+	return template.HTML(buf.String())
+}
+
+// barChart renders the buckets of a histogram as an inline SVG bar chart.
+func barChart(buckets []Bucket) template.HTML {
+	if len(buckets) == 0 {
+		return template.HTML(`<p class="empty">No data.</p>`)
+	}
+	max := 0
+	for _, b := range buckets {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	ymax := niceCeil(float64(max))
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	barW := plotW / float64(len(buckets))
+
+	buf := &bytes.Buffer{}
+	startChart(buf, "latency (secs)", "requests", ymax)
+	for i, b := range buckets {
+		x := chartLeft + float64(i)*barW
+		h := float64(b.Count) / ymax * plotH
+		fmt.Fprintf(buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%4.4f secs: %d requests (%.2f%%)</title></rect>`,
+			x+1, chartTop+plotH-h, barW-2, h, chartColors[0], b.Mark, b.Count, b.Frequency*100)
+		fmt.Fprintf(buf, `<text x="%.1f" y="%.1f" text-anchor="middle">%4.3f</text>`, x+barW/2, chartTop+plotH+14, b.Mark)
+	}
+	buf.WriteString(`</svg>`)
+	return template.HTML(buf.String())
+}
+
+// startChart writes the opening of an SVG chart with its axes, labels and
+// horizontal grid lines up to ymax.
+func startChart(buf *bytes.Buffer, xLabel, yLabel string, ymax float64) {
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" viewBox="0 0 %d %d">`, chartWidth, chartHeight)
+	for i := 0; i <= 4; i++ {
+		v := ymax * float64(i) / 4
+		y := chartTop + plotH - plotH*float64(i)/4
+		fmt.Fprintf(buf, `<line class="grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(buf, `<text x="%d" y="%.1f" text-anchor="end">%.3g</text>`, chartLeft-6, y+4, v)
+	}
+	fmt.Fprintf(buf, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%.1f"/>`, chartLeft, chartTop, chartLeft, chartTop+plotH)
+	fmt.Fprintf(buf, `<line class="axis" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, chartLeft, chartTop+plotH, chartWidth-chartRight, chartTop+plotH)
+	fmt.Fprintf(buf, `<text x="%d" y="%d" text-anchor="middle">%s</text>`,
+		chartLeft+(chartWidth-chartLeft-chartRight)/2, chartHeight-6, template.HTMLEscapeString(xLabel))
+	fmt.Fprintf(buf, `<text transform="translate(14 %.1f) rotate(-90)" text-anchor="middle">%s</text>`,
+		chartTop+plotH/2, template.HTMLEscapeString(yLabel))
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+var htmlTmpl = template.Must(template.New("html").Funcs(template.FuncMap{
+	"formatNumber": formatNumber,
+	"formatTime":   formatTime,
+	"pctl":         pctl,
+	"percent":      percent,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>loombench report - {{ .Run.StartTime.Format "2006-01-02 15:04:05" }}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 760px; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 12px 2px 0; text-align: left; vertical-align: top; }
+td.num { text-align: right; }
+.chart { width: 100%; height: auto; font-size: 11px; }
+.chart .grid { stroke: #eee; }
+.chart .axis { stroke: #888; }
+.empty { color: #888; }
+.warning { border: 1px solid #d62728; background: #fdf0f0; padding: 0 1em; }
+</style>
+</head>
+<body>
+<h1>loombench report</h1>
+<p>{{ .Run.ContractAddress }}.{{ .Run.ContractMethod }} on chain {{ .Run.ChainID }}, {{ .Run.StartTime.Format "2006-01-02 15:04:05 MST" }}</p>
+
+{{ with .Report.Overhead }}{{ if .Warnings }}<div class="warning">
+<p><strong>Warning:</strong> the client was the bottleneck of the run, the results may measure loombench rather than the chain:</p>
+<ul>
+{{ range .Warnings }}<li>{{ . }}</li>
+{{ end }}</ul>
+</div>
+{{ end }}{{ end }}
+<h2>Summary</h2>
+<table>
+<tr><td>Total</td><td class="num">{{ formatNumber .Report.Total.Seconds }} secs</td></tr>
+<tr><td>Requests</td><td class="num">{{ .Report.NumRes }}</td></tr>
+<tr><td>Requests/sec</td><td class="num">{{ formatNumber .Report.Rps }}</td></tr>
+<tr><td>Slowest</td><td class="num">{{ formatNumber .Report.Slowest }} secs</td></tr>
+<tr><td>Fastest</td><td class="num">{{ formatNumber .Report.Fastest }} secs</td></tr>
+<tr><td>Average</td><td class="num">{{ formatNumber .Report.Average }} secs</td></tr>
+<tr><td>Std. dev.</td><td class="num">{{ formatNumber .Report.StdDev }} secs</td></tr>
+{{ if gt .Report.NumWarmup 0 }}<tr><td>Warmup</td><td class="num">{{ .Report.NumWarmup }} requests ({{ .Report.WarmupErrors }} errors), excluded</td></tr>
+{{ end }}{{ if gt .Report.NumCancelled 0 }}<tr><td>Cancelled</td><td class="num">{{ .Report.NumCancelled }} requests, excluded</td></tr>
+{{ end }}<tr><td>New connections</td><td class="num">{{ .Report.ConnNew }}</td></tr>
+<tr><td>Reused connections</td><td class="num">{{ .Report.ConnReused }}</td></tr>
+</table>
+
+<h2>Latency over time</h2>
+{{ .LatencyChart }}
+
+<h2>Throughput over time</h2>
+{{ .ThroughputChart }}
+
+<h2>Latency distribution</h2>
+{{ .PercentileChart }}
+<table>
+{{ range .Report.LatencyDistribution }}<tr><td>p{{ .Percentage }}</td><td class="num">{{ formatNumber .Latency }} secs</td></tr>
+{{ end }}</table>
+
+<h2>Response time histogram</h2>
+{{ .Histogram }}
+
+<h2>Phases</h2>
+{{ if .Report.Phases }}<table>
+<tr><th>Phase</th><th>Requests</th><th>Average</th><th>p50</th><th>p90</th><th>p99</th><th>Slowest</th></tr>
+{{ range .Report.Phases }}<tr><td>{{ .Name }}</td><td class="num">{{ .Count }}</td><td class="num">{{ formatNumber .Average }}</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 50) }}</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 90) }}</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 99) }}</td><td class="num">{{ formatNumber .Slowest }}</td></tr>
+{{ end }}</table>
+<p>Latencies in secs.</p>
+{{ else }}<p class="empty">No data.</p>
+{{ end }}
+<h2>Operations</h2>
+{{ range .Ops }}<h3>{{ .Name }}</h3>
+<p>{{ .NumRes }} requests, {{ .Errors }} errors, {{ formatNumber .Rps }} requests/sec, average {{ formatNumber .Average }} secs, slowest {{ formatNumber .Slowest }} secs</p>
+{{ .Histogram }}
+{{ else }}<p class="empty">No data.</p>
+{{ end }}{{ if .Report.Nodes }}
+<h2>Nodes</h2>
+<table>
+<tr><th>Node</th><th>Requests</th><th>Errors</th><th>Requests/sec</th><th>Average</th><th>p99</th><th>Slowest</th></tr>
+{{ range $node, $s := .Report.Nodes }}<tr><td>{{ $node }}</td><td class="num">{{ $s.NumRes }}</td><td class="num">{{ $s.Errors }}</td><td class="num">{{ formatNumber $s.Rps }}</td><td class="num">{{ formatNumber $s.Average }}</td><td class="num">{{ formatNumber (pctl $s.LatencyDistribution 99) }}</td><td class="num">{{ formatNumber $s.Slowest }}</td></tr>
+{{ end }}</table>
+<p>Latencies in secs.</p>
+{{ end }}{{ if or .Report.NodeEvents (gt .Report.NumFailover 0) }}
+<h2>Failover</h2>
+<p>{{ .Report.NumFailover }} requests retried on another node.</p>
+{{ if .Report.NodeEvents }}<table>
+<tr><th>Time</th><th>Node</th><th>Event</th><th>Error</th></tr>
+{{ range .Report.NodeEvents }}<tr><td>{{ formatTime .Time }}</td><td>{{ .Node }}</td><td>{{ .Event }}</td><td>{{ .Error }}</td></tr>
+{{ end }}</table>
+{{ end }}{{ end }}{{ if .Report.AuxCalls }}
+<h2>Auxiliary calls</h2>
+<table>
+<tr><th>Method</th><th>Calls</th><th>Errors</th><th>Average</th><th>Slowest</th><th>Share of request time</th></tr>
+{{ range $method, $s := .Report.AuxCalls }}<tr><td>{{ $method }}</td><td class="num">{{ $s.Calls }}</td><td class="num">{{ $s.Errors }}</td><td class="num">{{ formatNumber $s.Average }} secs</td><td class="num">{{ formatNumber $s.Slowest }} secs</td><td class="num">{{ percent $s.Share }}</td></tr>
+{{ end }}</table>
+{{ end }}{{ with .Report.Retry }}
+<h2>Retries</h2>
+<table>
+<tr><td>First attempt</td><td class="num">{{ .FirstAttempt }} requests</td></tr>
+<tr><td>After retry</td><td class="num">{{ .AfterRetry }} requests</td></tr>
+<tr><td>Failed</td><td class="num">{{ .Failed }} requests</td></tr>
+<tr><td>Retries</td><td class="num">{{ .Retries }}</td></tr>
+<tr><td>Average after retry</td><td class="num">{{ formatNumber .Average }} secs</td></tr>
+<tr><td>p99 after retry</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 99) }} secs</td></tr>
+<tr><td>Slowest after retry</td><td class="num">{{ formatNumber .Slowest }} secs</td></tr>
+</table>
+{{ end }}{{ with .Report.Batch }}
+<h2>Batches</h2>
+<table>
+<tr><td>Batches</td><td class="num">{{ .Batches }}</td></tr>
+<tr><td>Requests</td><td class="num">{{ .Items }}</td></tr>
+<tr><td>Average size</td><td class="num">{{ formatNumber .AverageSize }} requests</td></tr>
+<tr><td>Average</td><td class="num">{{ formatNumber .Average }} secs</td></tr>
+<tr><td>p99</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 99) }} secs</td></tr>
+<tr><td>Slowest</td><td class="num">{{ formatNumber .Slowest }} secs</td></tr>
+<tr><td>Per request</td><td class="num">{{ formatNumber .PerItem }} secs</td></tr>
+</table>
+{{ end }}{{ with .Report.Verify }}
+<h2>Read-your-writes verification</h2>
+<table>
+<tr><td>Writes</td><td class="num">{{ .Writes }}</td></tr>
+<tr><td>Visible</td><td class="num">{{ .Visible }}</td></tr>
+<tr><td>Lost</td><td class="num">{{ .Lost }}</td></tr>
+<tr><td>Mismatched</td><td class="num">{{ .Mismatched }}</td></tr>
+<tr><td>Reads</td><td class="num">{{ .Reads }}</td></tr>
+<tr><td>Average staleness</td><td class="num">{{ formatNumber .Average }} secs</td></tr>
+<tr><td>p99 staleness</td><td class="num">{{ formatNumber (pctl .LatencyDistribution 99) }} secs</td></tr>
+<tr><td>Slowest staleness</td><td class="num">{{ formatNumber .Slowest }} secs</td></tr>
+</table>
+{{ end }}{{ with .Report.Audit }}
+<h2>Audit of written keys</h2>
+<table>
+<tr><td>Writes</td><td class="num">{{ .Writes }}</td></tr>
+<tr><td>Found</td><td class="num">{{ .Found }}</td></tr>
+<tr><td>Missing</td><td class="num">{{ .Missing }}</td></tr>
+<tr><td>Mismatched</td><td class="num">{{ .Mismatched }}</td></tr>
+<tr><td>Errors</td><td class="num">{{ .Errors }}</td></tr>
+{{ if gt .Unchecked 0 }}<tr><td>Unchecked</td><td class="num">{{ .Unchecked }}</td></tr>
+{{ end }}<tr><td>Durability</td><td class="num">{{ percent .Durability }}</td></tr>
+<tr><td>Total</td><td class="num">{{ formatNumber .Total.Seconds }} secs</td></tr>
+</table>
+{{ end }}{{ with .Report.Overhead }}
+<h2>Client overhead</h2>
+<table>
+{{ if gt .CPUUsage 0.0 }}<tr><td>CPU</td><td class="num">{{ percent .CPUUsage }} of {{ .CPUs }} CPUs, {{ formatNumber .UserCPU }} secs user, {{ formatNumber .SystemCPU }} secs system</td></tr>
+{{ end }}<tr><td>Scheduling delay</td><td class="num">{{ formatNumber .SchedDelayAverage }} secs average, {{ formatNumber .SchedDelayP99 }} secs p99, {{ formatNumber .SchedDelayMax }} secs slowest</td></tr>
+<tr><td>GC</td><td class="num">{{ .NumGC }} collections, {{ formatNumber .GCPauseTotal }} secs paused, {{ formatNumber .GCPauseMax }} secs longest pause</td></tr>
+<tr><td>Request time</td><td class="num">{{ percent .ClientShare }} in the client, {{ formatNumber .ClientTime }} secs client, {{ formatNumber .NetworkTime }} secs network</td></tr>
+</table>
+{{ end }}
+<h2>Errors</h2>
+{{ if .Errors }}<table>
+<tr><th>Count</th><th>%</th><th>Error</th></tr>
+{{ range .Errors }}<tr><td class="num">{{ .Count }}</td><td class="num">{{ printf "%.2f" .Percent }}</td><td>{{ .Error }}</td></tr>
+{{ end }}</table>
+{{ else }}<p class="empty">No errors.</p>
+{{ end }}
+<h2>Run</h2>
+<table>
+{{ range .Info }}<tr><td>{{ .Name }}</td><td>{{ .Value }}</td></tr>
+{{ end }}</table>
+
+<h2>Configuration</h2>
+<table>
+{{ range .Config }}<tr><td>{{ .Name }}</td><td>{{ .Value }}</td></tr>
+{{ end }}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,96 @@
+package requester
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+func TestHTMLReport(t *testing.T) {
+	dist := []LatencyDistribution{{Percentage: 50, Latency: 0.1}, {Percentage: 99, Latency: 0.5}}
+	info := RunInfo{
+		StartTime: time.Now(),
+		EndTime:   time.Now(),
+		Nodes: []Node{
+			{Name: "node-a", WriteURL: "http://a:46658/rpc", ReadURL: "http://a:46658/query", Weight: 3},
+			{Name: "node-b", WriteURL: "http://b:46658/rpc", ReadURL: "http://b:46658/query"},
+		},
+		Balance: BalanceWeighted,
+		Config: RunConfig{
+			TransactionType: "write",
+			N:               100,
+			C:               10,
+			Duration:        30 * time.Second,
+			Proxy:           "http://proxy:8080",
+			MaxIdleConns:    20,
+			TLSInsecure:     true,
+			Verify:          true,
+			VerifyTimeout:   5 * time.Second,
+			Retry: &loomclient.RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     100 * time.Millisecond,
+				Categories:  []string{loomclient.ErrCategoryTransport, loomclient.ErrCategoryTimeout},
+			},
+		},
+	}
+	snapshot := Report{
+		NumRes:              100,
+		Phases:              []PhaseReport{{Name: "dns", Count: 10, LatencyDistribution: dist}},
+		Nodes:               map[string]OpReport{"node-a": {NumRes: 75, LatencyDistribution: dist}},
+		NumFailover:         2,
+		NodeEvents:          []NodeEvent{{Time: time.Now(), Node: "node-b", Event: "ejected", Error: "connection refused"}},
+		AuxCalls:            map[string]AuxReport{"nonce": {Calls: 100, Share: 0.25}},
+		Retry:               &RetryReport{AfterRetry: 4, Retries: 5},
+		Batch:               &BatchReport{Batches: 10, Items: 100},
+		Verify:              &VerifyReport{Writes: 100, Lost: 1},
+		Audit:               &AuditReport{Writes: 100, Missing: 3},
+		Overhead:            &OverheadReport{CPUs: 4, Warnings: []string{"the client used 95.00% of the CPU time of 4 CPUs"}},
+		LatencyDistribution: dist,
+	}
+	doc := htmlReport{
+		Run:    info,
+		Report: snapshot,
+		Info:   infoRows(info),
+		Config: configRows(info.Config),
+	}
+	var buf bytes.Buffer
+	if err := htmlTmpl.Execute(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"the client was the bottleneck",
+		"CPU time of 4 CPUs",
+		"<h2>Phases</h2>",
+		"<h2>Nodes</h2>",
+		"<h2>Failover</h2>",
+		"connection refused",
+		"<h2>Auxiliary calls</h2>",
+		"<h2>Retries</h2>",
+		"<h2>Batches</h2>",
+		"<h2>Read-your-writes verification</h2>",
+		"<h2>Audit of written keys</h2>",
+		"<h2>Client overhead</h2>",
+		"<td>Node node-a</td><td>write http://a:46658/rpc, read http://a:46658/query, weight 3</td>",
+		"<td>Balance</td><td>weighted</td>",
+		"<td>proxy</td><td>http://proxy:8080</td>",
+		"<td>max_idle_conns</td><td>20</td>",
+		"<td>tls_insecure</td><td>true</td>",
+		"<td>verify_timeout</td><td>5s</td>",
+		"<td>retry.max_attempts</td><td>3</td>",
+		"<td>retry.categories</td><td>transport, timeout</td>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+	// Optional options that weren't set are left out.
+	for _, unwanted := range []string{"max_conns_per_host", "tls_client_cert", "batch_size", "retry.max_backoff"} {
+		if strings.Contains(out, "<td>"+unwanted+"</td>") {
+			t.Errorf("output has unset option %q", unwanted)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+	"github.com/jsimnz/loombench/version"
+)
+
+const (
+	// JSONSchema identifies documents written by the "json" output.
+	JSONSchema = "loombench-report"
+
+	// JSONSchemaVersion is the version of the JSON report schema. It is
+	// incremented whenever a field is renamed, removed or changes meaning,
+	// but not when fields are added.
+	JSONSchemaVersion = 1
+)
+
+// Timeout for querying the status of the node before a run.
+const statusTimeout = 5 * time.Second
+
+// JSONReport is the document written by the "json" output.
+type JSONReport struct {
+	Schema        string  `json:"schema"`
+	SchemaVersion int     `json:"schema_version"`
+	Run           RunInfo `json:"run"`
+	Report        Report  `json:"report"`
+}
+
+// RunInfo describes the run that produced a report.
+type RunInfo struct {
+	Version   string    `json:"loombench_version"`
+	GitCommit string    `json:"loombench_commit,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	ChainID         string `json:"chain_id"`
+	ContractAddress string `json:"contract_address"`
+	ContractMethod  string `json:"contract_method"`
+	WriteURL        string `json:"write_url"`
+	ReadURL         string `json:"read_url"`
+
+	// Node is the status of the node txs were submitted to, if it could be
+	// queried at the start of the run. With several nodes it is the first.
+	Node *NodeInfo `json:"node,omitempty"`
+
+	// Nodes and Balance are the nodes requests were distributed across and
+	// how, if there were several.
+	Nodes   []Node `json:"nodes,omitempty"`
+	Balance string `json:"balance,omitempty"`
+
+	Config RunConfig `json:"config"`
+}
+
+// NodeInfo identifies a Loom DAppChain node.
+type NodeInfo struct {
+	Moniker   string `json:"moniker"`
+	Network   string `json:"network"`
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit,omitempty"`
+	Height    int64  `json:"height"`
+}
+
+// RunConfig holds the options a run was made with.
+type RunConfig struct {
+	TransactionType    string        `json:"transaction_type"`
+	Ratio              float64       `json:"ratio"`
+	N                  int           `json:"n"`
+	C                  int           `json:"c"`
+	Duration           time.Duration `json:"duration"`
+	QPS                float64       `json:"qps"`
+	Timeout            int           `json:"timeout"`
+	Warmup             time.Duration `json:"warmup"`
+	WarmupN            int           `json:"warmup_n"`
+	Interval           time.Duration `json:"interval"`
+	UseRawRequest      bool          `json:"raw_request"`
+	UseFastJSON        bool          `json:"fast_json"`
+	H2                 bool          `json:"h2"`
+	DisableCompression bool          `json:"disable_compression"`
+	DisableKeepAlives  bool          `json:"disable_keepalive"`
+	DisableRedirects   bool          `json:"disable_redirects"`
+	Proxy              string        `json:"proxy,omitempty"`
+	MaxIdleConns       int           `json:"max_idle_conns,omitempty"`
+	MaxConnsPerHost    int           `json:"max_conns_per_host,omitempty"`
+	TLSCustomCA        bool          `json:"tls_custom_ca,omitempty"`
+	TLSClientCert      bool          `json:"tls_client_cert,omitempty"`
+	TLSInsecure        bool          `json:"tls_insecure,omitempty"`
+	CPUs               int           `json:"cpus"`
+	Verify             bool          `json:"verify"`
+	VerifyURL          string        `json:"verify_url,omitempty"`
+	VerifyTimeout      time.Duration `json:"verify_timeout,omitempty"`
+	Audit              bool          `json:"audit"`
+	AuditQPS           float64       `json:"audit_qps,omitempty"`
+	HealthCheck        time.Duration `json:"health_check,omitempty"`
+	Failover           bool          `json:"failover"`
+	BatchSize          int           `json:"batch_size,omitempty"`
+
+	Retry *loomclient.RetryPolicy `json:"retry,omitempty"`
+}
+
+func (b *Work) runInfo() RunInfo {
+	info := RunInfo{
+		Version:         version.Version,
+		GitCommit:       version.GitCommit,
+		ChainID:         b.ChainID,
+		ContractAddress: b.ContractAddress,
+		ContractMethod:  b.ContractMethod,
+		WriteURL:        b.WriteURL,
+		ReadURL:         b.ReadURL,
+		Config: RunConfig{
+			TransactionType:    b.workload(),
+			Ratio:              b.Ratio,
+			N:                  b.N,
+			C:                  b.C,
+			Duration:           b.Duration,
+			QPS:                b.QPS,
+			Timeout:            b.Timeout,
+			Warmup:             b.Warmup,
+			WarmupN:            b.WarmupN,
+			Interval:           b.interval(),
+			UseRawRequest:      b.UseRawRequest,
+			UseFastJSON:        b.UseFastJSON,
+			H2:                 b.H2,
+			DisableCompression: b.DisableCompression,
+			DisableKeepAlives:  b.DisableKeepAlives,
+			DisableRedirects:   b.DisableRedirects,
+			MaxIdleConns:       b.MaxIdleConns,
+			MaxConnsPerHost:    b.MaxConnsPerHost,
+			CPUs:               runtime.GOMAXPROCS(0),
+			Verify:             b.Verify,
+			VerifyURL:          b.VerifyURL,
+			VerifyTimeout:      b.VerifyTimeout,
+			Audit:              b.Audit,
+			AuditQPS:           b.AuditQPS,
+			HealthCheck:        b.HealthCheck,
+			Failover:           b.Failover,
+			BatchSize:          b.BatchSize,
+			Retry:              b.Retry,
+		},
+	}
+	if b.ProxyAddr != nil {
+		info.Config.Proxy = b.ProxyAddr.String()
+	}
+	if c := b.TLSConfig; c != nil {
+		info.Config.TLSCustomCA = c.RootCAs != nil
+		info.Config.TLSClientCert = len(c.Certificates) > 0
+		info.Config.TLSInsecure = c.InsecureSkipVerify
+	}
+	if nodes := b.nodes(); len(nodes) > 1 {
+		info.Nodes = nodes
+		info.Balance = b.balance()
+	}
+	return info
+}
+
+// nodeInfo queries the status of the node txs are submitted to. It returns
+// nil if the node can't be queried.
+func (b *Work) nodeInfo(ctx context.Context) *NodeInfo {
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: b.TLSConfig},
+		Timeout:   statusTimeout,
+	}
+	node := b.nodes()[0]
+	rpc := loomclient.NewDAppChainRPCClient(client, b.ChainID, node.WriteURL, node.ReadURL)
+	status, err := rpc.Status(ctx)
+	if err != nil {
+		return nil
+	}
+	return &NodeInfo{
+		Moniker:   status.NodeInfo.Moniker,
+		Network:   status.NodeInfo.Network,
+		Version:   status.NodeInfo.Version,
+		GitCommit: status.GitCommit(),
+		Height:    int64(status.SyncInfo.LatestBlockHeight),
+	}
+}
+
+func (r *report) printJSON() error {
+	doc := JSONReport{
+		Schema:        JSONSchema,
+		SchemaVersion: JSONSchemaVersion,
+		Run:           r.info,
+		Report:        r.snapshot(),
+	}
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,117 @@
+package requester
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "loombench"
+
+// metrics exposes client side metrics of a run for Prometheus to scrape, so
+// they can be correlated with the metrics of the nodes under test.
+type metrics struct {
+	registry *prometheus.Registry
+	server   *http.Server
+
+	requests   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	inFlight   prometheus.Gauge
+	targetRate prometheus.Gauge
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Number of completed requests by operation, result and error category.",
+		}, []string{"op", "result", "category"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of successful requests by operation and phase.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 17),
+		}, []string{"op", "phase"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_in_flight",
+			Help:      "Number of requests sent and not yet completed.",
+		}),
+		targetRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "target_rps",
+			Help:      "Target rate of requests per second, 0 if unlimited.",
+		}),
+	}
+	m.registry.MustRegister(m.requests, m.latency, m.inFlight, m.targetRate)
+	return m
+}
+
+// serve starts serving the metrics on addr.
+func (m *metrics) serve(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Handler: mux}
+	go m.server.Serve(l)
+	return nil
+}
+
+// linger keeps serving for d, or until ctx is done, and then closes the
+// server.
+func (m *metrics) linger(ctx context.Context, d time.Duration) {
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+		}
+		t.Stop()
+	}
+	m.close()
+}
+
+func (m *metrics) close() {
+	if m.server != nil {
+		m.server.Close()
+	}
+}
+
+func (m *metrics) observe(res *result) {
+	if res.err != nil {
+		m.requests.WithLabelValues(res.op, "error", res.category).Inc()
+		return
+	}
+	m.requests.WithLabelValues(res.op, "success", "").Inc()
+	m.latency.WithLabelValues(res.op, "total").Observe(res.duration.Seconds())
+	// Connection phases are only observed for the requests that went
+	// through them.
+	for _, p := range []struct {
+		phase string
+		d     time.Duration
+	}{
+		{"conn", res.connDuration},
+		{"dns", res.dnsDuration},
+		{"dial", res.dialDuration},
+		{"tls", res.tlsDuration},
+	} {
+		if p.d > 0 {
+			m.latency.WithLabelValues(res.op, p.phase).Observe(p.d.Seconds())
+		}
+	}
+	m.latency.WithLabelValues(res.op, "req").Observe(res.reqDuration.Seconds())
+	m.latency.WithLabelValues(res.op, "delay").Observe(res.delayDuration.Seconds())
+	m.latency.WithLabelValues(res.op, "res").Observe(res.resDuration.Seconds())
+	for _, a := range res.aux {
+		m.latency.WithLabelValues(res.op, "aux_"+a.method).Observe(a.duration.Seconds())
+	}
+}
@@ -0,0 +1,182 @@
+package requester
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// Node is a Loom DAppChain node requests are sent to.
+type Node struct {
+	// Name identifies the node in the report, the host of its write URL
+	// unless another node shares it.
+	Name     string `json:"name"`
+	WriteURL string `json:"write_url"`
+	ReadURL  string `json:"read_url"`
+
+	// Weight is the share of the requests sent to the node with
+	// BalanceWeighted, relative to the other nodes. Default is 1.
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// Strategies to distribute requests across nodes.
+const (
+	BalanceRoundRobin = "round-robin" // each worker cycles through the nodes
+	BalanceRandom     = "random"      // each request goes to a random node
+	BalanceWeighted   = "weighted"    // like random, in proportion to the weights
+	BalanceSticky     = "sticky"      // each account always uses the same node
+)
+
+func (n Node) weight() float64 {
+	if n.Weight <= 0 {
+		return 1
+	}
+	return n.Weight
+}
+
+// ParseNodes returns the nodes given by comma separated lists of write and
+// read URLs, paired in order. A single URL in either list is shared by all
+// the nodes. weights, if not empty, are the weights of the nodes.
+func ParseNodes(writeURLs, readURLs string, weights []float64) ([]Node, error) {
+	writes := splitList(writeURLs)
+	reads := splitList(readURLs)
+	if len(writes) == 0 || len(reads) == 0 {
+		return nil, fmt.Errorf("requester: no write or read URL")
+	}
+	n := len(writes)
+	if len(reads) > n {
+		n = len(reads)
+	}
+	if (len(writes) != 1 && len(writes) != n) || (len(reads) != 1 && len(reads) != n) {
+		return nil, fmt.Errorf("requester: %d write URLs don't pair with %d read URLs", len(writes), len(reads))
+	}
+	if len(weights) != 0 && len(weights) != n {
+		return nil, fmt.Errorf("requester: %d weights given for %d nodes", len(weights), n)
+	}
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = Node{WriteURL: writes[0], ReadURL: reads[0], Weight: 1}
+		if len(writes) > 1 {
+			nodes[i].WriteURL = writes[i]
+		}
+		if len(reads) > 1 {
+			nodes[i].ReadURL = reads[i]
+		}
+		if len(weights) > 0 {
+			if weights[i] <= 0 {
+				return nil, fmt.Errorf("requester: weight %v is not greater than 0", weights[i])
+			}
+			nodes[i].Weight = weights[i]
+		}
+	}
+	// Name the nodes after the host they differ by.
+	for _, name := range []func(n Node) string{
+		func(n Node) string { return urlHost(n.WriteURL) },
+		func(n Node) string { return urlHost(n.ReadURL) },
+		func(n Node) string { return n.WriteURL + " " + n.ReadURL },
+	} {
+		seen := make(map[string]bool)
+		for i := range nodes {
+			nodes[i].Name = name(nodes[i])
+			seen[nodes[i].Name] = true
+		}
+		if len(seen) == n {
+			return nodes, nil
+		}
+	}
+	return nil, fmt.Errorf("requester: nodes are given more than once")
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func urlHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	return u.Host
+}
+
+// workerNode holds the clients a worker sends its requests to a node with.
+type workerNode struct {
+	name   string
+	client *loomclient.ContractClient
+	rpc    *loomclient.DAppChainRPCClient
+	verify *loomclient.ContractClient
+}
+
+// nodes returns the nodes of the run.
+func (b *Work) nodes() []Node {
+	if len(b.Nodes) > 0 {
+		return b.Nodes
+	}
+	return []Node{{Name: urlHost(b.WriteURL), WriteURL: b.WriteURL, ReadURL: b.ReadURL, Weight: 1}}
+}
+
+func (b *Work) balance() string {
+	if b.Balance == "" {
+		return BalanceRoundRobin
+	}
+	return b.Balance
+}
+
+// stickyNode returns the node an account always uses with BalanceSticky.
+func stickyNode(account string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(account))
+	return int(h.Sum32() % uint32(n))
+}
+
+// pickNode returns the node the next request of ws is sent to, skipping
+// the nodes ejected by health checks. sticky is the node of the worker's
+// account.
+func (b *Work) pickNode(ws *WorkerState, nodes []*workerNode, sticky int) *workerNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return b.healthyNode(nodes, b.pickIndex(ws, nodes, sticky))
+}
+
+func (b *Work) pickIndex(ws *WorkerState, nodes []*workerNode, sticky int) int {
+	switch b.Balance {
+	case BalanceRandom:
+		return ws.Rand.Intn(len(nodes))
+	case BalanceWeighted:
+		var total float64
+		for _, n := range b.Nodes {
+			total += n.weight()
+		}
+		x := ws.Rand.Float64() * total
+		for i, n := range b.Nodes {
+			if x < n.weight() {
+				return i
+			}
+			x -= n.weight()
+		}
+		return len(nodes) - 1
+	case BalanceSticky:
+		return sticky
+	default:
+		return (ws.ID + int(ws.Seq%uint64(len(nodes)))) % len(nodes)
+	}
+}
+
+// use sends the next operation of ws to n.
+func (ws *WorkerState) use(n *workerNode) {
+	ws.node = n
+	ws.Node = n.name
+	ws.Client = n.client
+	ws.RPC = n.rpc
+	ws.verifyClient = n.verify
+}
@@ -0,0 +1,168 @@
+package requester
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  string
+		reads   string
+		weights []float64
+		want    []Node
+	}{
+		{
+			name:   "single",
+			writes: "http://a:46658/rpc",
+			reads:  "http://a:46658/query",
+			want: []Node{
+				{Name: "a:46658", WriteURL: "http://a:46658/rpc", ReadURL: "http://a:46658/query", Weight: 1},
+			},
+		},
+		{
+			name:   "paired",
+			writes: "http://a:46658/rpc, http://b:46658/rpc",
+			reads:  "http://a:46658/query,http://b:46658/query",
+			want: []Node{
+				{Name: "a:46658", WriteURL: "http://a:46658/rpc", ReadURL: "http://a:46658/query", Weight: 1},
+				{Name: "b:46658", WriteURL: "http://b:46658/rpc", ReadURL: "http://b:46658/query", Weight: 1},
+			},
+		},
+		{
+			name:   "shared read",
+			writes: "http://a:46658/rpc,http://b:46658/rpc,",
+			reads:  "http://r:46658/query",
+			want: []Node{
+				{Name: "a:46658", WriteURL: "http://a:46658/rpc", ReadURL: "http://r:46658/query", Weight: 1},
+				{Name: "b:46658", WriteURL: "http://b:46658/rpc", ReadURL: "http://r:46658/query", Weight: 1},
+			},
+		},
+		{
+			name:   "named by read host",
+			writes: "http://w:46658/rpc",
+			reads:  "http://a:46658/query,http://b:46658/query",
+			want: []Node{
+				{Name: "a:46658", WriteURL: "http://w:46658/rpc", ReadURL: "http://a:46658/query", Weight: 1},
+				{Name: "b:46658", WriteURL: "http://w:46658/rpc", ReadURL: "http://b:46658/query", Weight: 1},
+			},
+		},
+		{
+			name:   "named by urls",
+			writes: "http://a:46658/rpc,http://a:46658/rpc2",
+			reads:  "http://a:46658/query,http://a:46658/query2",
+			want: []Node{
+				{Name: "http://a:46658/rpc http://a:46658/query", WriteURL: "http://a:46658/rpc", ReadURL: "http://a:46658/query", Weight: 1},
+				{Name: "http://a:46658/rpc2 http://a:46658/query2", WriteURL: "http://a:46658/rpc2", ReadURL: "http://a:46658/query2", Weight: 1},
+			},
+		},
+		{
+			name:    "weighted",
+			writes:  "http://a:46658/rpc,http://b:46658/rpc",
+			reads:   "http://a:46658/query,http://b:46658/query",
+			weights: []float64{3, 0.5},
+			want: []Node{
+				{Name: "a:46658", WriteURL: "http://a:46658/rpc", ReadURL: "http://a:46658/query", Weight: 3},
+				{Name: "b:46658", WriteURL: "http://b:46658/rpc", ReadURL: "http://b:46658/query", Weight: 0.5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseNodes(tt.writes, tt.reads, tt.weights)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseNodes() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseNodesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  string
+		reads   string
+		weights []float64
+		err     string
+	}{
+		{"no write", "", "http://a/query", nil, "no write or read URL"},
+		{"no read", "http://a/rpc", " , ", nil, "no write or read URL"},
+		{"unpaired", "http://a/rpc,http://b/rpc", "http://a/query,http://b/query,http://c/query", nil, "don't pair"},
+		{"too few weights", "http://a/rpc,http://b/rpc", "http://a/query", []float64{1}, "1 weights given for 2 nodes"},
+		{"too many weights", "http://a/rpc", "http://a/query", []float64{1, 2}, "2 weights given for 1 nodes"},
+		{"zero weight", "http://a/rpc,http://b/rpc", "http://a/query", []float64{1, 0}, "not greater than 0"},
+		{"negative weight", "http://a/rpc,http://b/rpc", "http://a/query", []float64{-1, 1}, "not greater than 0"},
+		{"duplicate", "http://a/rpc,http://a/rpc", "http://a/query", nil, "more than once"},
+	}
+	for _, tt := range tests {
+		_, err := ParseNodes(tt.writes, tt.reads, tt.weights)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestPickIndex(t *testing.T) {
+	nodes := make([]*workerNode, 3)
+	b := &Work{Nodes: []Node{{Weight: 1}, {Weight: 1}, {Weight: 1}}}
+
+	ws := &WorkerState{ID: 1, Rand: rand.New(rand.NewSource(1))}
+	var got []int
+	for ws.Seq = 0; ws.Seq < 6; ws.Seq++ {
+		got = append(got, b.pickIndex(ws, nodes, 0))
+	}
+	if want := []int{1, 2, 0, 1, 2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("round-robin picked %v, want %v", got, want)
+	}
+
+	b.Balance = BalanceSticky
+	for i := 0; i < 10; i++ {
+		if got := b.pickIndex(ws, nodes, 2); got != 2 {
+			t.Fatalf("sticky picked %d, want 2", got)
+		}
+	}
+}
+
+func TestPickIndexDistribution(t *testing.T) {
+	tests := []struct {
+		balance string
+		weights []float64
+	}{
+		{BalanceRandom, []float64{1, 1, 1}},
+		{BalanceWeighted, []float64{1, 1, 1}},
+		{BalanceWeighted, []float64{6, 3, 1}},
+		{BalanceWeighted, []float64{0.1, 0, 0.1}}, // 0 is the default of 1
+		{BalanceWeighted, []float64{1000, 1, 1}},
+	}
+	const picks = 100000
+	for _, tt := range tests {
+		b := &Work{Balance: tt.balance}
+		var total float64
+		for _, w := range tt.weights {
+			b.Nodes = append(b.Nodes, Node{Weight: w})
+			total += Node{Weight: w}.weight()
+		}
+		nodes := make([]*workerNode, len(tt.weights))
+		ws := &WorkerState{Rand: rand.New(rand.NewSource(1))}
+
+		counts := make([]int, len(nodes))
+		for i := 0; i < picks; i++ {
+			counts[b.pickIndex(ws, nodes, 0)]++
+		}
+		for i, n := range b.Nodes {
+			want := n.weight() / total
+			if tt.balance == BalanceRandom {
+				want = 1 / float64(len(nodes))
+			}
+			if got := float64(counts[i]) / picks; math.Abs(got-want) > 0.01 {
+				t.Errorf("%s %v: node %d got %.4f of the requests, want %.4f", tt.balance, tt.weights, i, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// Thresholds beyond which the client is reported as the bottleneck of a run.
+const (
+	saturatedCPU        = 0.9                   // share of the CPU time of GOMAXPROCS used
+	saturatedSchedDelay = 10 * time.Millisecond // p99 of the scheduling delay
+	saturatedGC         = 0.05                  // share of the run spent in GC pauses
+	saturatedClient     = 0.5                   // share of the request time spent in the client
+)
+
+// schedProbeInterval is the time the goroutine measuring the scheduling
+// delay sleeps between its wakeups.
+const schedProbeInterval = 10 * time.Millisecond
+
+// overheadStats tracks the resources the client uses during a run.
+type overheadStats struct {
+	start               time.Time
+	user, system        time.Duration // CPU time of the process at the start
+	cpuOK               bool
+	mem                 runtime.MemStats // at the start
+	sched               *hdrHistogram    // written by probe until done is closed
+	done                chan struct{}
+	clientTime, netTime time.Duration // added by the reporter
+	report              *OverheadReport
+}
+
+// startOverhead starts tracking the resources of the client until ctx is
+// done and stop is called.
+func startOverhead(ctx context.Context) *overheadStats {
+	o := &overheadStats{
+		start: time.Now(),
+		sched: newHDRHistogram(),
+		done:  make(chan struct{}),
+	}
+	o.user, o.system, o.cpuOK = processCPU()
+	runtime.ReadMemStats(&o.mem)
+	go o.probe(ctx)
+	return o
+}
+
+// probe measures the scheduling delay: how late a sleeping goroutine runs
+// again, which grows when the CPUs are too busy to run the goroutines that
+// are ready, such as the ones reading responses.
+func (o *overheadStats) probe(ctx context.Context) {
+	defer close(o.done)
+	t := time.NewTimer(schedProbeInterval)
+	defer t.Stop()
+	for {
+		slept := time.Now()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		o.sched.record(time.Since(slept) - schedProbeInterval)
+		t.Reset(schedProbeInterval)
+	}
+}
+
+// add splits the duration of res between the client, signing and encoding
+// the request and decoding the response, and the network, from obtaining a
+// connection to reading the response of each call. Failed requests, those
+// made in several attempts and all but the first of a batch are left out.
+func (o *overheadStats) add(res *result) {
+	if res.err != nil || res.attempts > 1 || res.failedOver || (res.batched && res.batchSize == 0) {
+		return
+	}
+	network := res.connDuration + res.reqDuration + res.delayDuration + res.resDuration
+	for _, a := range res.aux {
+		network += a.duration
+	}
+	if network > res.duration {
+		network = res.duration
+	}
+	o.netTime += network
+	o.clientTime += res.duration - network
+}
+
+// stop ends the tracking, once the probe stopped with the context passed
+// to startOverhead.
+func (o *overheadStats) stop() {
+	<-o.done
+	wall := time.Since(o.start)
+	cpus := runtime.GOMAXPROCS(0)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	rep := &OverheadReport{
+		CPUs:              cpus,
+		SchedDelayAverage: o.sched.mean(),
+		SchedDelayP99:     o.sched.quantile(99),
+		SchedDelayMax:     o.sched.max.Seconds(),
+		NumGC:             mem.NumGC - o.mem.NumGC,
+		GCPauseTotal:      time.Duration(mem.PauseTotalNs - o.mem.PauseTotalNs).Seconds(),
+	}
+	if user, system, ok := processCPU(); ok && o.cpuOK {
+		rep.UserCPU = (user - o.user).Seconds()
+		rep.SystemCPU = (system - o.system).Seconds()
+		if wall > 0 {
+			rep.CPUUsage = (rep.UserCPU + rep.SystemCPU) / (wall.Seconds() * float64(cpus))
+		}
+	}
+	// The pauses of the last 256 GCs are kept in a circular buffer.
+	for n := mem.NumGC; n > o.mem.NumGC && mem.NumGC-n < uint32(len(mem.PauseNs)); n-- {
+		if p := time.Duration(mem.PauseNs[(n+255)%256]).Seconds(); p > rep.GCPauseMax {
+			rep.GCPauseMax = p
+		}
+	}
+	rep.ClientTime = o.clientTime.Seconds()
+	rep.NetworkTime = o.netTime.Seconds()
+	if total := o.clientTime + o.netTime; total > 0 {
+		rep.ClientShare = float64(o.clientTime) / float64(total)
+	}
+
+	if rep.CPUUsage > saturatedCPU {
+		rep.Warnings = append(rep.Warnings, fmt.Sprintf("the client used %s of the CPU time of %d CPUs", percent(rep.CPUUsage), cpus))
+	}
+	if rep.SchedDelayP99 > saturatedSchedDelay.Seconds() {
+		rep.Warnings = append(rep.Warnings, fmt.Sprintf("goroutines waited up to %s secs (p99) to be scheduled", formatNumber(rep.SchedDelayP99)))
+	}
+	if wall > 0 && rep.GCPauseTotal/wall.Seconds() > saturatedGC {
+		rep.Warnings = append(rep.Warnings, fmt.Sprintf("GC pauses took %s of the run", percent(rep.GCPauseTotal/wall.Seconds())))
+	}
+	if rep.ClientShare > saturatedClient {
+		rep.Warnings = append(rep.Warnings, fmt.Sprintf("requests spent %s of their time in the client rather than on the network", percent(rep.ClientShare)))
+	}
+	o.report = rep
+}
+
+// OverheadReport describes the resources the client used during a run, to
+// tell whether its results measure the chain or the load generator.
+type OverheadReport struct {
+	// CPUs is GOMAXPROCS. UserCPU and SystemCPU are the CPU time used by
+	// the process, in seconds, and CPUUsage their share of the CPU time of
+	// CPUs during the run. They are zero where the CPU time of the process
+	// isn't available.
+	CPUs      int     `json:"cpus"`
+	UserCPU   float64 `json:"user_cpu"`
+	SystemCPU float64 `json:"system_cpu"`
+	CPUUsage  float64 `json:"cpu_usage"`
+
+	// SchedDelayAverage, SchedDelayP99 and SchedDelayMax describe how late
+	// a goroutine woke up from a sleep, in seconds.
+	SchedDelayAverage float64 `json:"sched_delay_average"`
+	SchedDelayP99     float64 `json:"sched_delay_p99"`
+	SchedDelayMax     float64 `json:"sched_delay_max"`
+
+	// NumGC is the number of garbage collections during the run, and
+	// GCPauseTotal and GCPauseMax their stop-the-world pauses, in seconds.
+	NumGC        uint32  `json:"num_gc"`
+	GCPauseTotal float64 `json:"gc_pause_total"`
+	GCPauseMax   float64 `json:"gc_pause_max"`
+
+	// ClientTime is the total time requests spent in the client, signing
+	// and encoding them and decoding their responses, and NetworkTime the
+	// time they spent on the network, in seconds. ClientShare is the share
+	// of the client.
+	ClientTime  float64 `json:"client_time"`
+	NetworkTime float64 `json:"network_time"`
+	ClientShare float64 `json:"client_share"`
+
+	// Warnings tell why the client was the bottleneck of the run, if it
+	// was.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// startProfiles starts the CPU profile of the run, if requested.
+func (b *Work) startProfiles() error {
+	if b.CPUProfile == nil {
+		return nil
+	}
+	if err := pprof.StartCPUProfile(b.CPUProfile); err != nil {
+		b.CPUProfile = nil
+		return err
+	}
+	return nil
+}
+
+func (b *Work) stopCPUProfile() {
+	if b.CPUProfile != nil {
+		pprof.StopCPUProfile()
+	}
+}
+
+// writeHeapProfile writes the heap profile of the run, if requested. It
+// collects garbage first, so it is written once the overhead is measured.
+func (b *Work) writeHeapProfile() error {
+	if b.HeapProfile == nil {
+		return nil
+	}
+	runtime.GC() // the heap profile is as of the last GC
+	return pprof.WriteHeapProfile(b.HeapProfile)
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
+	"time"
 )
 
 func newTemplate(output string) *template.Template {
@@ -27,16 +28,28 @@ func newTemplate(output string) *template.Template {
 	switch outputTmpl {
 	case "":
 		outputTmpl = defaultTmpl
-	case "csv":
-		outputTmpl = csvTmpl
 	}
 	return template.Must(template.New("tmpl").Funcs(tmplFuncMap).Parse(outputTmpl))
 }
 
 var tmplFuncMap = template.FuncMap{
 	"formatNumber": formatNumber,
+	"formatTime":   formatTime,
 	"histogram":    histogram,
 	"jsonify":      jsonify,
+	"pctl":         pctl,
+	"percent":      percent,
+}
+
+func percent(fraction float64) string {
+	return fmt.Sprintf("%.2f%%", fraction*100)
+}
+
+// pctl returns the latency at percentile p of a distribution, or 0 if the
+// distribution is empty.
+func pctl(dist []LatencyDistribution, p float64) float64 {
+	v, _ := percentile(dist, p)
+	return v
 }
 
 func jsonify(v interface{}) string {
@@ -48,6 +61,10 @@ func formatNumber(duration float64) string {
 	return fmt.Sprintf("%4.4f", duration)
 }
 
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
 func histogram(buckets []Bucket) string {
 	max := 0
 	for _, b := range buckets {
@@ -74,7 +91,10 @@ Summary:
   Slowest:	{{ formatNumber .Slowest }} secs
   Fastest:	{{ formatNumber .Fastest }} secs
   Average:	{{ formatNumber .Average }} secs
-  Requests/sec:	{{ formatNumber .Rps }}
+  Std. dev.:	{{ formatNumber .StdDev }} secs
+  Requests/sec:	{{ formatNumber .Rps }}{{ if gt .NumWarmup 0 }}
+  Warmup:	{{ .NumWarmup }} requests ({{ .WarmupErrors }} errors) in {{ formatNumber .WarmupTotal.Seconds }} secs, excluded from statistics{{ end }}{{ if gt .NumCancelled 0 }}
+  Cancelled:	{{ .NumCancelled }} requests aborted by the end of the run{{ end }}
   {{ if gt .SizeTotal 0 }}
   Total data:	{{ .SizeTotal }} bytes
   Size/request:	{{ .SizeReq }} bytes{{ end }}
@@ -83,23 +103,80 @@ Response time histogram:
 {{ histogram .Histogram }}
 
 Latency distribution:{{ range .LatencyDistribution }}
-  {{ .Percentage }}%% in {{ formatNumber .Latency }} secs{{ end }}
+  {{ .Percentage }}% in {{ formatNumber .Latency }} secs{{ end }}
 
 Details (average, fastest, slowest):
-  {{/* DNS+dialup:	{{ formatNumber .AvgConn }} secs, {{ formatNumber .Fastest }} secs, {{ formatNumber .Slowest }} secs
-  DNS-lookup:	{{ formatNumber .AvgDNS }} secs, {{ formatNumber .DnsMax }} secs, {{ formatNumber .DnsMin }} secs */}}
-  req write:	{{ formatNumber .AvgReq }} secs, {{ formatNumber .ReqMax }} secs, {{ formatNumber .ReqMin }} secs
-  resp wait:	{{ formatNumber .AvgDelay }} secs, {{ formatNumber .DelayMax }} secs, {{ formatNumber .DelayMin }} secs
-  resp read:	{{ formatNumber .AvgRes }} secs, {{ formatNumber .ResMax }} secs, {{ formatNumber .ResMin }} secs
+  DNS+dialup:	{{ formatNumber .AvgConn }} secs, {{ formatNumber .ConnMin }} secs, {{ formatNumber .ConnMax }} secs
+  DNS-lookup:	{{ formatNumber .AvgDNS }} secs, {{ formatNumber .DnsMin }} secs, {{ formatNumber .DnsMax }} secs
+  TCP connect:	{{ formatNumber .AvgDial }} secs, {{ formatNumber .DialMin }} secs, {{ formatNumber .DialMax }} secs
+  TLS handshake:	{{ formatNumber .AvgTLS }} secs, {{ formatNumber .TLSMin }} secs, {{ formatNumber .TLSMax }} secs
+  req write:	{{ formatNumber .AvgReq }} secs, {{ formatNumber .ReqMin }} secs, {{ formatNumber .ReqMax }} secs
+  resp wait:	{{ formatNumber .AvgDelay }} secs, {{ formatNumber .DelayMin }} secs, {{ formatNumber .DelayMax }} secs
+  resp read:	{{ formatNumber .AvgRes }} secs, {{ formatNumber .ResMin }} secs, {{ formatNumber .ResMax }} secs
+
+Phase distribution (requests, p50, p90, p99):{{ range .Phases }}
+  {{ .Name }}:	{{ .Count }}, {{ formatNumber (pctl .LatencyDistribution 50) }} secs, {{ formatNumber (pctl .LatencyDistribution 90) }} secs, {{ formatNumber (pctl .LatencyDistribution 99) }} secs{{ end }}
+
+Connections:
+  New:	{{ .ConnNew }}
+  Reused:	{{ .ConnReused }}
+{{ if gt (len .AuxCalls) 0 }}
+Auxiliary calls (calls, errors, average, slowest, share of request time):{{ range $method, $s := .AuxCalls }}
+  {{ $method }}:	{{ $s.Calls }}, {{ $s.Errors }}, {{ formatNumber $s.Average }} secs, {{ formatNumber $s.Slowest }} secs, {{ percent $s.Share }}{{ end }}
+{{ end }}{{ with .Overhead }}
+Client overhead:{{ if gt .CPUUsage 0.0 }}
+  CPU:	{{ percent .CPUUsage }} of {{ .CPUs }} CPUs, {{ formatNumber .UserCPU }} secs user, {{ formatNumber .SystemCPU }} secs system{{ end }}
+  Scheduling delay:	{{ formatNumber .SchedDelayAverage }} secs average, {{ formatNumber .SchedDelayP99 }} secs p99, {{ formatNumber .SchedDelayMax }} secs slowest
+  GC:	{{ .NumGC }} collections, {{ formatNumber .GCPauseTotal }} secs paused, {{ formatNumber .GCPauseMax }} secs longest pause
+  Request time:	{{ percent .ClientShare }} in the client (signing, encoding, decoding), {{ formatNumber .ClientTime }} secs client, {{ formatNumber .NetworkTime }} secs network
+{{ end }}{{ if .Retry }}
+Retries:
+  First attempt:	{{ .Retry.FirstAttempt }} requests
+  After retry:	{{ .Retry.AfterRetry }} requests, {{ formatNumber .Retry.Average }} secs average, {{ formatNumber (pctl .Retry.LatencyDistribution 99) }} secs p99, {{ formatNumber .Retry.Slowest }} secs slowest
+  Failed:	{{ .Retry.Failed }} requests
+  Retries:	{{ .Retry.Retries }}
+{{ end }}{{ if .Batch }}
+Batches:
+  Batches:	{{ .Batch.Batches }}, {{ formatNumber .Batch.AverageSize }} requests average
+  Latency:	{{ formatNumber .Batch.Average }} secs average, {{ formatNumber (pctl .Batch.LatencyDistribution 99) }} secs p99, {{ formatNumber .Batch.Slowest }} secs slowest
+  Per request:	{{ formatNumber .Batch.PerItem }} secs
+{{ end }}{{ if .Verify }}
+Read-your-writes verification:
+  Writes:	{{ .Verify.Writes }}
+  Visible:	{{ .Verify.Visible }}
+  Lost:	{{ .Verify.Lost }}
+  Mismatched:	{{ .Verify.Mismatched }}
+  Staleness:	{{ formatNumber .Verify.Average }} secs average, {{ formatNumber (pctl .Verify.LatencyDistribution 99) }} secs p99, {{ formatNumber .Verify.Slowest }} secs slowest
+{{ end }}{{ if .Audit }}
+Audit of written keys:
+  Writes:	{{ .Audit.Writes }}
+  Found:	{{ .Audit.Found }}
+  Missing:	{{ .Audit.Missing }}
+  Mismatched:	{{ .Audit.Mismatched }}
+  Errors:	{{ .Audit.Errors }}{{ if gt .Audit.Unchecked 0 }}
+  Unchecked:	{{ .Audit.Unchecked }}{{ end }}
+  Durability:	{{ percent .Audit.Durability }}
+  Total:	{{ formatNumber .Audit.Total.Seconds }} secs
+{{ end }}
+Operations (requests, errors, req/s, average, slowest):{{ range $op, $s := .Ops }}
+  {{ $op }}:	{{ $s.NumRes }}, {{ $s.Errors }}, {{ formatNumber $s.Rps }}, {{ formatNumber $s.Average }} secs, {{ formatNumber $s.Slowest }} secs{{ end }}
 
+{{ if .Nodes }}
+Nodes (requests, errors, req/s, average, p99, slowest):{{ range $node, $s := .Nodes }}
+  {{ $node }}:	{{ $s.NumRes }}, {{ $s.Errors }}, {{ formatNumber $s.Rps }}, {{ formatNumber $s.Average }} secs, {{ formatNumber (pctl $s.LatencyDistribution 99) }} secs, {{ formatNumber $s.Slowest }} secs{{ end }}
+{{ end }}{{ if or .NodeEvents (gt .NumFailover 0) }}
+Failover:
+  Retried:	{{ .NumFailover }} requests on another node{{ range .NodeEvents }}
+  {{ formatTime .Time }}	{{ .Node }} {{ .Event }}{{ if .Error }}: {{ .Error }}{{ end }}{{ end }}
+{{ end }}
 Status code distribution:{{ range $code, $num := .StatusCodeDist }}
   [{{ $code }}]	{{ $num }} responses{{ end }}
 
 {{ if gt (len .ErrorDist) 0 }}Error distribution:{{ range $err, $num := .ErrorDist }}
-  [{{ $num }}]	{{ $err }}{{ end }}{{ end }}
-`
-	csvTmpl = `{{ $connLats := .ConnLats }}{{ $dnsLats := .DnsLats }}{{ $dnsLats := .DnsLats }}{{ $reqLats := .ReqLats }}{{ $delayLats := .DelayLats }}{{ $resLats := .ResLats }}
-response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read{{ range $i, $v := .Lats }}
-{{ formatNumber $v }},{{ formatNumber (index $connLats $i) }},{{ formatNumber (index $dnsLats $i) }},{{ formatNumber (index $reqLats $i) }},{{ formatNumber (index $delayLats $i) }},{{ formatNumber (index $resLats $i) }}{{ end }}
+  [{{ $num }}]	{{ $err }}{{ end }}{{ end }}{{ if .Overhead }}{{ if .Overhead.Warnings }}
+
+WARNING: the client was the bottleneck of the run, the results may measure
+loombench rather than the chain:{{ range .Overhead.Warnings }}
+  - {{ . }}{{ end }}{{ end }}{{ end }}
 `
 )
@@ -18,8 +18,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
-	"sort"
 	"time"
 )
 
@@ -27,26 +25,31 @@ const (
 	barChar = "■"
 )
 
-// We report for max 1M results.
-const maxRes = 1000000
+// Percentiles reported in the latency distribution.
+var pctls = []float64{10, 25, 50, 75, 90, 95, 99, 99.9, 99.99, 99.999}
 
 type report struct {
-	avgTotal float64
-	fastest  float64
-	slowest  float64
-	average  float64
-	rps      float64
-
-	avgConn   float64
-	avgDNS    float64
-	avgReq    float64
-	avgRes    float64
-	avgDelay  float64
-	connLats  []float64
-	dnsLats   []float64
-	reqLats   []float64
-	resLats   []float64
-	delayLats []float64
+	rps  float64
+	info RunInfo
+
+	// Latencies of successful results, in total and per phase. The conn,
+	// dns, dial and tls phases are only recorded for requests that went
+	// through them.
+	lats      *hdrHistogram
+	connLats  *hdrHistogram
+	dnsLats   *hdrHistogram
+	dialLats  *hdrHistogram
+	tlsLats   *hdrHistogram
+	reqLats   *hdrHistogram
+	resLats   *hdrHistogram
+	delayLats *hdrHistogram
+
+	ops map[string]*opStats
+
+	// Auxiliary calls by method, and the total duration of the requests
+	// they were made for.
+	aux         map[string]*opStats
+	durationSum float64
 
 	results chan *result
 	done    chan bool
@@ -54,257 +57,603 @@ type report struct {
 
 	errorDist      map[string]int
 	statusCodeDist map[int]int
-	lats           []float64
 	sizeTotal      int64
 	numRes         int64
+	numCancelled   int64
+	connNew        int64
+	connReused     int64
 	output         string
 
+	// Warmup results are counted here and excluded from everything else.
+	numWarmup    int64
+	warmupErrs   int64
+	measureStart time.Duration // send time of the first non-warmup request
+	warmupTotal  time.Duration
+
+	// Time series of the run, snapshotted every interval.
+	start      time.Duration
+	interval   time.Duration
+	win        window
+	intervals  []Interval
+	live       *liveTable
+	timeSeries *timeSeriesCSV
+
+	metrics  *metrics
+	log      *resultLog
+	onResult func(Result)
+
+	// err is the first error writing the per-request log or printing the
+	// report, returned by the run once it is finished.
+	err error
+
+	verify   *verifyStats
+	retry    *retryStats
+	batch    *batchStats
+	overhead *overheadStats
+
+	// Results by node, if the run sent requests to several nodes, the
+	// number retried on another node and the health changes of the nodes.
+	nodes       map[string]*opStats
+	numFailover int64
+	events      []NodeEvent
+
+	// Successful writes kept for the audit, and its outcome.
+	auditing bool
+	written  []auditEntry
+	audit    *AuditReport
+
+	// quiet disables printing the summary.
+	quiet bool
+
 	w io.Writer
 }
 
-func newReport(w io.Writer, results chan *result, output string, n int) *report {
-	cap := min(n, maxRes)
-	return &report{
+// opStats aggregates the results of one type of operation.
+type opStats struct {
+	numRes int64
+	errors int64
+	lats   *hdrHistogram
+}
+
+func newReport(w io.Writer, results chan *result, output string) *report {
+	r := &report{
 		output:         output,
 		results:        results,
 		done:           make(chan bool, 1),
 		statusCodeDist: make(map[int]int),
 		errorDist:      make(map[string]int),
 		w:              w,
-		connLats:       make([]float64, 0, cap),
-		dnsLats:        make([]float64, 0, cap),
-		reqLats:        make([]float64, 0, cap),
-		resLats:        make([]float64, 0, cap),
-		delayLats:      make([]float64, 0, cap),
-		lats:           make([]float64, 0, cap),
+		lats:           newHDRHistogram(),
+		connLats:       newHDRHistogram(),
+		dnsLats:        newHDRHistogram(),
+		dialLats:       newHDRHistogram(),
+		tlsLats:        newHDRHistogram(),
+		reqLats:        newHDRHistogram(),
+		resLats:        newHDRHistogram(),
+		delayLats:      newHDRHistogram(),
+		ops:            make(map[string]*opStats),
+		aux:            make(map[string]*opStats),
+		win:            window{lats: newHDRHistogram()},
 	}
+	return r
 }
 
 func runReporter(r *report) {
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	r.win.start = r.start
+
 	// Loop will continue until channel is closed
-	for res := range r.results {
-		r.numRes++
+	for {
+		select {
+		case res, ok := <-r.results:
+			if !ok {
+				r.flushInterval()
+				r.flushLog()
+				// Signal reporter is done.
+				r.done <- true
+				return
+			}
+			r.add(res)
+		case <-tick:
+			r.flushInterval()
+			r.flushLog()
+		}
+	}
+}
+
+func (r *report) add(res *result) {
+	if r.metrics != nil {
+		r.metrics.observe(res)
+	}
+	if r.log != nil {
+		if err := r.log.write(res); err != nil {
+			r.setErr(err)
+			r.log = nil
+		}
+	}
+	if r.onResult != nil {
+		r.onResult(publicResult(res, r.info.StartTime, r.start))
+	}
+	if r.auditing && res.written != nil {
+		r.written = append(r.written, auditEntry{op: res.written, txHash: res.txHash, height: res.height})
+	}
+	if res.cancelled {
+		// Aborted by the end of the run, not a failure of the request.
+		r.numCancelled++
+		return
+	}
+	if r.interval > 0 {
+		r.win.add(res)
+	}
+	if res.warmup {
+		r.numWarmup++
 		if res.err != nil {
-			r.errorDist[res.err.Error()]++
+			r.warmupErrs++
+		}
+		return
+	}
+	if r.numRes == 0 || res.start < r.measureStart {
+		r.measureStart = res.start
+	}
+	r.numRes++
+	if res.failedOver {
+		r.numFailover++
+	}
+	if r.retry != nil {
+		r.retry.add(res)
+	}
+	if r.batch != nil {
+		r.batch.add(res)
+	}
+	if r.overhead != nil {
+		r.overhead.add(res)
+	}
+
+	op, ok := r.ops[res.op]
+	if !ok {
+		op = &opStats{lats: newHDRHistogram()}
+		r.ops[res.op] = op
+	}
+	op.numRes++
+	var node *opStats
+	if r.nodes != nil {
+		node, ok = r.nodes[res.node]
+		if !ok {
+			node = &opStats{lats: newHDRHistogram()}
+			r.nodes[res.node] = node
+		}
+		node.numRes++
+	}
+
+	r.durationSum += res.duration.Seconds()
+	for _, a := range res.aux {
+		s, ok := r.aux[a.method]
+		if !ok {
+			s = &opStats{lats: newHDRHistogram()}
+			r.aux[a.method] = s
+		}
+		s.numRes++
+		if a.err {
+			s.errors++
+		}
+		s.lats.record(a.duration)
+	}
+
+	if res.gotConn {
+		if res.connReused {
+			r.connReused++
 		} else {
-			r.avgTotal += res.duration.Seconds()
-			r.avgConn += res.connDuration.Seconds()
-			r.avgDelay += res.delayDuration.Seconds()
-			r.avgDNS += res.dnsDuration.Seconds()
-			r.avgReq += res.reqDuration.Seconds()
-			r.avgRes += res.resDuration.Seconds()
-			if len(r.resLats) < maxRes {
-				r.lats = append(r.lats, res.duration.Seconds())
-				r.connLats = append(r.connLats, res.connDuration.Seconds())
-				r.dnsLats = append(r.dnsLats, res.dnsDuration.Seconds())
-				r.reqLats = append(r.reqLats, res.reqDuration.Seconds())
-				r.delayLats = append(r.delayLats, res.delayDuration.Seconds())
-				r.resLats = append(r.resLats, res.resDuration.Seconds())
-			}
-			r.statusCodeDist[res.statusCode]++
-			if res.contentLength > 0 {
-				r.sizeTotal += res.contentLength
-			}
+			r.connNew++
+		}
+	}
+
+	if res.err != nil {
+		op.errors++
+		if node != nil {
+			node.errors++
+		}
+		r.errorDist[res.err.Error()]++
+	} else {
+		op.lats.record(res.duration)
+		if node != nil {
+			node.lats.record(res.duration)
+		}
+		r.lats.record(res.duration)
+		recordPhase(r.connLats, res.connDuration)
+		recordPhase(r.dnsLats, res.dnsDuration)
+		recordPhase(r.dialLats, res.dialDuration)
+		recordPhase(r.tlsLats, res.tlsDuration)
+		if !res.batched || res.batchSize > 0 {
+			// The phases of a batch are those of its first request.
+			r.reqLats.record(res.reqDuration)
+			r.delayLats.record(res.delayDuration)
+			r.resLats.record(res.resDuration)
+		}
+		r.statusCodeDist[res.statusCode]++
+		if res.contentLength > 0 {
+			r.sizeTotal += res.contentLength
 		}
 	}
-	// Signal reporter is done.
-	r.done <- true
 }
 
-func (r *report) finalize(total time.Duration) {
-	r.total = total
-	r.rps = float64(r.numRes) / r.total.Seconds()
-	r.average = r.avgTotal / float64(len(r.lats))
-	r.avgConn = r.avgConn / float64(len(r.lats))
-	r.avgDelay = r.avgDelay / float64(len(r.lats))
-	r.avgDNS = r.avgDNS / float64(len(r.lats))
-	r.avgReq = r.avgReq / float64(len(r.lats))
-	r.avgRes = r.avgRes / float64(len(r.lats))
-	r.print()
+func (r *report) opReport(op *opStats) OpReport {
+	var rps float64
+	if r.total > 0 {
+		rps = float64(op.numRes) / r.total.Seconds()
+	}
+	return OpReport{
+		NumRes:              op.numRes,
+		Errors:              op.errors,
+		Rps:                 rps,
+		Average:             op.lats.mean(),
+		Fastest:             op.lats.min.Seconds(),
+		Slowest:             op.lats.max.Seconds(),
+		StdDev:              op.lats.stdDev(),
+		LatencyDistribution: op.lats.distribution(pctls),
+		Histogram:           op.lats.buckets(10),
+	}
+}
+
+// recordPhase records the duration of a phase that only some requests go
+// through, such as DNS lookup on new connections.
+func recordPhase(h *hdrHistogram, d time.Duration) {
+	if d > 0 {
+		h.record(d)
+	}
+}
+
+// flushInterval records the interval in progress and emits it to the live
+// table and time series outputs.
+func (r *report) flushInterval() {
+	if r.interval <= 0 {
+		return
+	}
+	iv := r.win.flush(now())
+	iv.Start -= r.start
+	r.intervals = append(r.intervals, iv)
+	if r.live != nil {
+		r.live.print(iv)
+	}
+	if r.timeSeries != nil {
+		r.timeSeries.write(iv)
+	}
+}
+
+// flushLog writes the buffered entries of the per-request log, so it can be
+// followed while the run is in progress.
+func (r *report) flushLog() {
+	if r.log == nil {
+		return
+	}
+	if err := r.log.flush(); err != nil {
+		r.setErr(err)
+		r.log = nil
+	}
+}
+
+func (r *report) finalize(start, end time.Duration) {
+	r.total = end - start
+	if r.numWarmup > 0 && r.numRes > 0 {
+		// Measure throughput from the end of the warmup period.
+		r.warmupTotal = r.measureStart - start
+		r.total = end - r.measureStart
+	}
+	if r.total > 0 {
+		r.rps = float64(r.numRes) / r.total.Seconds()
+	}
+	if !r.quiet {
+		r.print()
+	}
 }
 
 func (r *report) print() {
+	if r.output == "csv" {
+		// Results have already been streamed to the result log.
+		return
+	}
+	if r.output == "json" {
+		if err := r.printJSON(); err != nil {
+			r.setErr(err)
+		}
+		return
+	}
+	if r.output == "html" {
+		if err := r.printHTML(); err != nil {
+			r.setErr(err)
+		}
+		return
+	}
 	buf := &bytes.Buffer{}
 	if err := newTemplate(r.output).Execute(buf, r.snapshot()); err != nil {
-		log.Println("error:", err.Error())
+		r.setErr(err)
 		return
 	}
-	r.printf(buf.String())
+	r.printf("%s", buf.String())
 
 	r.printf("\n")
 }
 
+// setErr records err, unless an error was already recorded.
+func (r *report) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
 func (r *report) printf(s string, v ...interface{}) {
 	fmt.Fprintf(r.w, s, v...)
 }
 
 func (r *report) snapshot() Report {
 	snapshot := Report{
-		AvgTotal:       r.avgTotal,
-		Average:        r.average,
+		AvgTotal:       r.lats.sum,
+		Average:        r.lats.mean(),
+		StdDev:         r.lats.stdDev(),
 		Rps:            r.rps,
 		SizeTotal:      r.sizeTotal,
-		AvgConn:        r.avgConn,
-		AvgDNS:         r.avgDNS,
-		AvgReq:         r.avgReq,
-		AvgRes:         r.avgRes,
-		AvgDelay:       r.avgDelay,
+		AvgConn:        r.connLats.mean(),
+		AvgDNS:         r.dnsLats.mean(),
+		AvgDial:        r.dialLats.mean(),
+		AvgTLS:         r.tlsLats.mean(),
+		AvgReq:         r.reqLats.mean(),
+		AvgRes:         r.resLats.mean(),
+		AvgDelay:       r.delayLats.mean(),
 		Total:          r.total,
 		ErrorDist:      r.errorDist,
 		StatusCodeDist: r.statusCodeDist,
 		NumRes:         r.numRes,
-		Lats:           make([]float64, len(r.lats)),
-		ConnLats:       make([]float64, len(r.lats)),
-		DnsLats:        make([]float64, len(r.lats)),
-		ReqLats:        make([]float64, len(r.lats)),
-		ResLats:        make([]float64, len(r.lats)),
-		DelayLats:      make([]float64, len(r.lats)),
+		NumCancelled:   r.numCancelled,
+		NumFailover:    r.numFailover,
+		NodeEvents:     r.events,
+		ConnNew:        r.connNew,
+		ConnReused:     r.connReused,
+		NumWarmup:      r.numWarmup,
+		WarmupErrors:   r.warmupErrs,
+		WarmupTotal:    r.warmupTotal,
+		Intervals:      r.intervals,
+		Ops:            make(map[string]OpReport, len(r.ops)),
+		AuxCalls:       make(map[string]AuxReport, len(r.aux)),
+	}
+	if r.verify != nil {
+		snapshot.Verify = r.verify.report()
+	}
+	if r.retry != nil {
+		snapshot.Retry = r.retry.report()
+	}
+	if r.batch != nil {
+		snapshot.Batch = r.batch.report()
+	}
+	if r.overhead != nil {
+		snapshot.Overhead = r.overhead.report
+	}
+	snapshot.Audit = r.audit
+
+	for method, s := range r.aux {
+		aux := AuxReport{
+			Calls:               s.numRes,
+			Errors:              s.errors,
+			Average:             s.lats.mean(),
+			Fastest:             s.lats.min.Seconds(),
+			Slowest:             s.lats.max.Seconds(),
+			LatencyDistribution: s.lats.distribution(pctls),
+		}
+		if r.durationSum > 0 {
+			aux.Share = s.lats.sum / r.durationSum
+		}
+		snapshot.AuxCalls[method] = aux
 	}
 
-	if len(r.lats) == 0 {
+	for name, op := range r.ops {
+		snapshot.Ops[name] = r.opReport(op)
+	}
+	if r.nodes != nil {
+		snapshot.Nodes = make(map[string]OpReport, len(r.nodes))
+		for name, node := range r.nodes {
+			snapshot.Nodes[name] = r.opReport(node)
+		}
+	}
+
+	if r.lats.count() == 0 {
 		return snapshot
 	}
 
-	snapshot.SizeReq = r.sizeTotal / int64(len(r.lats))
-
-	copy(snapshot.Lats, r.lats)
-	copy(snapshot.ConnLats, r.connLats)
-	copy(snapshot.DnsLats, r.dnsLats)
-	copy(snapshot.ReqLats, r.reqLats)
-	copy(snapshot.ResLats, r.resLats)
-	copy(snapshot.DelayLats, r.delayLats)
-
-	sort.Float64s(r.lats)
-	r.fastest = r.lats[0]
-	r.slowest = r.lats[len(r.lats)-1]
-
-	sort.Float64s(r.connLats)
-	sort.Float64s(r.dnsLats)
-	sort.Float64s(r.reqLats)
-	sort.Float64s(r.resLats)
-	sort.Float64s(r.delayLats)
-
-	snapshot.Histogram = r.histogram()
-	snapshot.LatencyDistribution = r.latencies()
-
-	snapshot.Fastest = r.fastest
-	snapshot.Slowest = r.slowest
-	snapshot.ConnMax = r.connLats[0]
-	snapshot.ConnMin = r.connLats[len(r.connLats)-1]
-	snapshot.DnsMax = r.dnsLats[0]
-	snapshot.DnsMin = r.dnsLats[len(r.dnsLats)-1]
-	snapshot.ReqMax = r.reqLats[0]
-	snapshot.ReqMin = r.reqLats[len(r.reqLats)-1]
-	snapshot.DelayMax = r.delayLats[0]
-	snapshot.DelayMin = r.delayLats[len(r.delayLats)-1]
-	snapshot.ResMax = r.resLats[0]
-	snapshot.ResMin = r.resLats[len(r.resLats)-1]
+	snapshot.SizeReq = r.sizeTotal / r.lats.count()
+
+	snapshot.Histogram = r.lats.buckets(10)
+	snapshot.LatencyDistribution = r.lats.distribution(pctls)
+
+	snapshot.Fastest = r.lats.min.Seconds()
+	snapshot.Slowest = r.lats.max.Seconds()
+	snapshot.ConnMin = r.connLats.min.Seconds()
+	snapshot.ConnMax = r.connLats.max.Seconds()
+	snapshot.DnsMin = r.dnsLats.min.Seconds()
+	snapshot.DnsMax = r.dnsLats.max.Seconds()
+	snapshot.DialMin = r.dialLats.min.Seconds()
+	snapshot.DialMax = r.dialLats.max.Seconds()
+	snapshot.TLSMin = r.tlsLats.min.Seconds()
+	snapshot.TLSMax = r.tlsLats.max.Seconds()
+	snapshot.ReqMin = r.reqLats.min.Seconds()
+	snapshot.ReqMax = r.reqLats.max.Seconds()
+	snapshot.DelayMin = r.delayLats.min.Seconds()
+	snapshot.DelayMax = r.delayLats.max.Seconds()
+	snapshot.ResMin = r.resLats.min.Seconds()
+	snapshot.ResMax = r.resLats.max.Seconds()
+
+	for _, p := range []struct {
+		name string
+		lats *hdrHistogram
+	}{
+		{"dns", r.dnsLats},
+		{"dial", r.dialLats},
+		{"tls", r.tlsLats},
+		{"conn", r.connLats},
+		{"req", r.reqLats},
+		{"delay", r.delayLats},
+		{"res", r.resLats},
+	} {
+		snapshot.Phases = append(snapshot.Phases, PhaseReport{
+			Name:                p.name,
+			Count:               p.lats.count(),
+			Average:             p.lats.mean(),
+			Fastest:             p.lats.min.Seconds(),
+			Slowest:             p.lats.max.Seconds(),
+			LatencyDistribution: p.lats.distribution(pctls),
+		})
+	}
 
 	return snapshot
 }
 
-func (r *report) latencies() []LatencyDistribution {
-	pctls := []int{10, 25, 50, 75, 90, 95, 99}
-	data := make([]float64, len(pctls))
-	j := 0
-	for i := 0; i < len(r.lats) && j < len(pctls); i++ {
-		current := i * 100 / len(r.lats)
-		if current >= pctls[j] {
-			data[j] = r.lats[i]
-			j++
-		}
-	}
-	res := make([]LatencyDistribution, len(pctls))
-	for i := 0; i < len(pctls); i++ {
-		if data[i] > 0 {
-			res[i] = LatencyDistribution{Percentage: pctls[i], Latency: data[i]}
-		}
-	}
-	return res
+type Report struct {
+	AvgTotal float64 `json:"avg_total"`
+	Fastest  float64 `json:"fastest"`
+	Slowest  float64 `json:"slowest"`
+	Average  float64 `json:"average"`
+	StdDev   float64 `json:"std_dev"`
+	Rps      float64 `json:"rps"`
+
+	AvgConn  float64 `json:"avg_conn"`
+	AvgDNS   float64 `json:"avg_dns"`
+	AvgDial  float64 `json:"avg_dial"`
+	AvgTLS   float64 `json:"avg_tls"`
+	AvgReq   float64 `json:"avg_req"`
+	AvgRes   float64 `json:"avg_res"`
+	AvgDelay float64 `json:"avg_delay"`
+	ConnMax  float64 `json:"conn_max"`
+	ConnMin  float64 `json:"conn_min"`
+	DnsMax   float64 `json:"dns_max"`
+	DnsMin   float64 `json:"dns_min"`
+	DialMax  float64 `json:"dial_max"`
+	DialMin  float64 `json:"dial_min"`
+	TLSMax   float64 `json:"tls_max"`
+	TLSMin   float64 `json:"tls_min"`
+	ReqMax   float64 `json:"req_max"`
+	ReqMin   float64 `json:"req_min"`
+	ResMax   float64 `json:"res_max"`
+	ResMin   float64 `json:"res_min"`
+	DelayMax float64 `json:"delay_max"`
+	DelayMin float64 `json:"delay_min"`
+
+	Total time.Duration `json:"total"`
+
+	ErrorDist      map[string]int `json:"error_dist"`
+	StatusCodeDist map[int]int    `json:"status_code_dist"`
+	SizeTotal      int64          `json:"size_total"`
+	SizeReq        int64          `json:"size_req"`
+	NumRes         int64          `json:"num_res"`
+
+	// NumCancelled counts the requests aborted by the end of the run,
+	// which are excluded from everything else.
+	NumCancelled int64 `json:"num_cancelled"`
+
+	// ConnNew and ConnReused count the requests that opened a new
+	// connection and those that reused an idle one.
+	ConnNew    int64 `json:"conn_new"`
+	ConnReused int64 `json:"conn_reused"`
+
+	// Phases summarizes each phase of the requests that went through it:
+	// dns, dial (tcp connect), tls, conn (dns, dial and tls), req (request
+	// write), delay (server wait) and res (response read).
+	Phases []PhaseReport `json:"phases"`
+
+	NumWarmup    int64         `json:"num_warmup"`
+	WarmupErrors int64         `json:"warmup_errors"`
+	WarmupTotal  time.Duration `json:"warmup_total"`
+
+	Intervals []Interval `json:"intervals"`
+
+	// Ops breaks the results down by the type of operation performed.
+	Ops map[string]OpReport `json:"ops"`
+
+	// Nodes breaks the results down by the node requests were sent to, if
+	// the run sent requests to several nodes.
+	Nodes map[string]OpReport `json:"nodes,omitempty"`
+
+	// NumFailover counts the requests retried on another node, and
+	// NodeEvents lists the ejections and readmissions of nodes by health
+	// checks, in order.
+	NumFailover int64       `json:"num_failover"`
+	NodeEvents  []NodeEvent `json:"node_events,omitempty"`
+
+	// AuxCalls summarizes the calls made for requests besides the ones
+	// performing their operation, such as nonce lookups, by RPC method.
+	AuxCalls map[string]AuxReport `json:"aux_calls"`
+
+	// Retry summarizes the attempts of the requests, if the run retried
+	// failed ones.
+	Retry *RetryReport `json:"retry,omitempty"`
+
+	// Batch summarizes the JSON-RPC batches, if the run sent requests in
+	// batches.
+	Batch *BatchReport `json:"batch,omitempty"`
+
+	// Overhead describes the resources the client used, once the run is
+	// done.
+	Overhead *OverheadReport `json:"overhead,omitempty"`
+
+	// Verify summarizes the read back of writes, if the run verified them.
+	Verify *VerifyReport `json:"verify,omitempty"`
+
+	// Audit summarizes the read back of the writes after the run, if the
+	// run audited them.
+	Audit *AuditReport `json:"audit,omitempty"`
+
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+	Histogram           []Bucket              `json:"histogram"`
 }
 
-func (r *report) histogram() []Bucket {
-	bc := 10
-	buckets := make([]float64, bc+1)
-	counts := make([]int, bc+1)
-	bs := (r.slowest - r.fastest) / float64(bc)
-	for i := 0; i < bc; i++ {
-		buckets[i] = r.fastest + bs*float64(i)
-	}
-	buckets[bc] = r.slowest
-	var bi int
-	var max int
-	for i := 0; i < len(r.lats); {
-		if r.lats[i] <= buckets[bi] {
-			i++
-			counts[bi]++
-			if max < counts[bi] {
-				max = counts[bi]
-			}
-		} else if bi < len(buckets)-1 {
-			bi++
-		}
-	}
-	res := make([]Bucket, len(buckets))
-	for i := 0; i < len(buckets); i++ {
-		res[i] = Bucket{
-			Mark:      buckets[i],
-			Count:     counts[i],
-			Frequency: float64(counts[i]) / float64(len(r.lats)),
-		}
-	}
-	return res
+// OpReport summarizes the results of one type of operation.
+type OpReport struct {
+	NumRes  int64   `json:"num_res"`
+	Errors  int64   `json:"errors"`
+	Rps     float64 `json:"rps"`
+	Average float64 `json:"average"`
+	Fastest float64 `json:"fastest"`
+	Slowest float64 `json:"slowest"`
+	StdDev  float64 `json:"std_dev"`
+
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+	Histogram           []Bucket              `json:"histogram"`
 }
 
-type Report struct {
-	AvgTotal float64
-	Fastest  float64
-	Slowest  float64
-	Average  float64
-	Rps      float64
-
-	AvgConn  float64
-	AvgDNS   float64
-	AvgReq   float64
-	AvgRes   float64
-	AvgDelay float64
-	ConnMax  float64
-	ConnMin  float64
-	DnsMax   float64
-	DnsMin   float64
-	ReqMax   float64
-	ReqMin   float64
-	ResMax   float64
-	ResMin   float64
-	DelayMax float64
-	DelayMin float64
-
-	Lats      []float64
-	ConnLats  []float64
-	DnsLats   []float64
-	ReqLats   []float64
-	ResLats   []float64
-	DelayLats []float64
-
-	Total time.Duration
-
-	ErrorDist      map[string]int
-	StatusCodeDist map[int]int
-	SizeTotal      int64
-	SizeReq        int64
-	NumRes         int64
-
-	LatencyDistribution []LatencyDistribution
-	Histogram           []Bucket
+// AuxReport summarizes the auxiliary calls to one RPC method.
+type AuxReport struct {
+	Calls   int64   `json:"calls"`
+	Errors  int64   `json:"errors"`
+	Average float64 `json:"average"`
+	Fastest float64 `json:"fastest"`
+	Slowest float64 `json:"slowest"`
+
+	// Share is the fraction of the total duration of requests spent in
+	// these calls.
+	Share float64 `json:"share"`
+
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+}
+
+// PhaseReport summarizes the duration of one phase of the requests.
+type PhaseReport struct {
+	Name    string  `json:"name"`
+	Count   int64   `json:"count"`
+	Average float64 `json:"average"`
+	Fastest float64 `json:"fastest"`
+	Slowest float64 `json:"slowest"`
+
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
 }
 
 type LatencyDistribution struct {
-	Percentage int
-	Latency    float64
+	Percentage float64 `json:"percentage"`
+	Latency    float64 `json:"latency"`
 }
 
 type Bucket struct {
-	Mark      float64
-	Count     int
-	Frequency float64
+	Mark      float64 `json:"mark"`
+	Count     int     `json:"count"`
+	Frequency float64 `json:"frequency"`
 }
@@ -17,15 +17,18 @@ package requester
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
-	"net/http/httptrace"
 	"net/url"
 	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jsimnz/loombench/loomclient"
@@ -34,7 +37,6 @@ import (
 	"github.com/loomnetwork/go-loom/auth"
 	// "github.com/mailru/easyjson"
 	"golang.org/x/crypto/ed25519"
-	"golang.org/x/net/http2"
 )
 
 // Max size of the buffer of result channel.
@@ -43,21 +45,54 @@ const maxIdleConn = 500
 
 type result struct {
 	err           error
+	category      string        // error category, if err is set
+	cancelled     bool          // the request was aborted by the end of the run
+	op            string        // name of the operation performed
+	node          string        // name of the node the request was sent to
+	failedOver    bool          // the request was retried on another node
+	attempts      int           // number of times the request was made
+	batchSize     int           // size of the batch the request is the first of
+	batched       bool          // the request was sent in a batch
+	start         time.Duration // time the request was sent, relative to startTime
+	warmup        bool          // request was sent during the warmup period
 	statusCode    int
 	duration      time.Duration
 	connDuration  time.Duration // connection setup(DNS lookup + Dial up) duration
 	dnsDuration   time.Duration // dns lookup duration
+	dialDuration  time.Duration // tcp connect duration
+	tlsDuration   time.Duration // tls handshake duration
 	reqDuration   time.Duration // request "write" duration
 	resDuration   time.Duration // response "read" duration
 	delayDuration time.Duration // delay between response and request
 	contentLength int64
+
+	// gotConn is set if a connection was obtained for the request, and
+	// connReused if it was an idle connection rather than a new one. The
+	// conn, dns, dial and tls phases only happen on new connections.
+	gotConn    bool
+	connReused bool
+
+	// aux are the calls made for the request besides the one performing
+	// its operation, such as nonce lookups.
+	aux []auxCall
+
+	// written is the successful write of the request, to audit.
+	written Verifiable
+
+	// Chain identifiers of the tx, if known.
+	account string
+	nonce   uint64
+	txHash  string
+	height  int64
 }
 
 type Work struct {
-	// Type of transactions to be used in the benchmark.
+	// TransactionType is the name of the workload generating the
+	// operations of the run, one of Workloads(). Default is WorkloadCall.
 	TransactionType string
 
-	// Ratio of transaction types.
+	// Ratio is the fraction of operations that are reads, for workloads
+	// mixing reads and writes.
 	Ratio float64
 
 	// Request is the request to be made.
@@ -72,23 +107,52 @@ type Work struct {
 	UseRawRequest bool
 
 	// UseFastJSON is an option to use an alternate JSON encoder to increase performace.
+	// It encodes and decodes the raw requests with the easyjson marshalers and
+	// pooled buffers, see DAppChainRPCClient.UseFastJSON.
 	UseFastJSON bool
 
 	// N is the total number of requests to make.
 	N int
 
+	// Duration is the length of the run. If non-zero, the run stops when
+	// it elapses, even if fewer than N requests were made.
+	Duration time.Duration
+
 	// C is the concurrency level, the number of concurrent workers to run.
 	C int
 
-	// H2 is an option to make HTTP/2 requests
+	// H2 is an option to make HTTP/2 requests, negotiated with TLS to
+	// https:// nodes and with prior knowledge (h2c) to the others. The h2c
+	// requests ignore ProxyAddr, DisableKeepAlives and MaxConnsPerHost.
 	H2 bool
 
+	// MaxIdleConns is the number of idle connections kept open to each
+	// node. Default is C, up to 500.
+	MaxIdleConns int
+
+	// MaxConnsPerHost limits the number of connections to each node,
+	// including the ones in use. Requests wait for a connection once the
+	// limit is reached. Zero means no limit. It doesn't apply with H2.
+	MaxConnsPerHost int
+
+	// TLSConfig is the TLS configuration of the connections to https://
+	// nodes, see loomclient.TLSOptions. Optional.
+	TLSConfig *tls.Config
+
 	// Timeout in seconds.
 	Timeout int
 
 	// Qps is the rate limit in queries per second.
 	QPS float64
 
+	// Warmup is the duration at the start of the run during which requests
+	// are made but excluded from the summary statistics.
+	Warmup time.Duration
+
+	// WarmupN is the number of requests at the start of the run that are made
+	// but excluded from the summary statistics.
+	WarmupN int
+
 	// DisableCompression is an option to disable compression in response
 	DisableCompression bool
 
@@ -98,8 +162,10 @@ type Work struct {
 	// DisableRedirects is an option to prevent the following of HTTP redirects
 	DisableRedirects bool
 
-	// Output represents the output type. If "csv" is provided, the
-	// output will be dumped as a csv stream.
+	// Output represents the output type. If "csv" is provided, every
+	// result is streamed to Writer as the csv result log while the run
+	// is in progress, unless ResultLog is set. If "json" is provided, the
+	// report and run metadata will be written as a JSONReport document.
 	Output string
 
 	// ProxyAddr is the address of HTTP proxy server in the format on "host:port".
@@ -121,22 +187,137 @@ type Work struct {
 	// Loom Read URL
 	ReadURL string
 
+	// Nodes are the nodes to send requests to, see ParseNodes. If empty,
+	// requests are sent to WriteURL and ReadURL.
+	Nodes []Node
+
+	// Balance is the strategy to distribute requests across Nodes, one of
+	// BalanceRoundRobin, BalanceRandom, BalanceWeighted or BalanceSticky.
+	// Default is BalanceRoundRobin.
+	Balance string
+
+	// HealthCheck is the time between the health checks of the nodes,
+	// which call the status RPC of their write URL and query a nonce from
+	// their read URL. Nodes failing two checks in a row, on either URL,
+	// are ejected from rotation until they pass one again, and the changes
+	// are listed in the report. Zero disables health checks.
+	HealthCheck time.Duration
+
+	// Failover retries a request that couldn't reach its node, or timed
+	// out, once on the next healthy node. The duration of the request
+	// includes both attempts. Raw requests aren't failed over.
+	Failover bool
+
+	// Retry, if set, is the policy failed requests are retried with. The
+	// duration of a request includes all of its attempts and the backoff
+	// between them, and the report tells apart the requests that succeeded
+	// at the first attempt from those that needed a retry. Raw requests
+	// are retried with the same signed tx.
+	Retry *loomclient.RetryPolicy
+
+	// CPUProfile and HeapProfile, if set, receive the pprof CPU profile of
+	// the client during the run and its heap profile at the end. The report
+	// always includes the CPU, scheduling delay and GC overhead of the
+	// client, and warns if the client was the bottleneck of the run.
+	CPUProfile  io.Writer
+	HeapProfile io.Writer
+
+	// BatchSize, if greater than one, is the number of requests of a worker
+	// sent together in a JSON-RPC batch, the txs in one and the queries in
+	// another. The requests of a batch share its duration, and are neither
+	// retried nor failed over. Ignored with UseRawRequest.
+	BatchSize int
+
 	// Priate Key to transaction signing
 	PrivateKey string
 
+	// Verify reads back every successful write of ops implementing
+	// Verifiable until the written value is visible, counting lost and
+	// mismatched writes and the staleness of reads in the report. The
+	// writes are verified in the background, workers only wait for a
+	// verifier once a backlog of 256 writes each is queued. Warmup writes
+	// aren't verified.
+	Verify bool
+
+	// VerifyURL is the read URL the writes are read back from, to verify
+	// them against another node. Default is ReadURL.
+	VerifyURL string
+
+	// VerifyTimeout is the time a write has to become visible before it
+	// counts as lost. Default is 10s.
+	VerifyTimeout time.Duration
+
+	// Audit keeps every successful write of ops implementing Verifiable
+	// and reads them all back once the run is over, from VerifyURL if set,
+	// counting the ones missing or mismatched in the report.
+	Audit bool
+
+	// AuditQPS is the rate of the reads of the audit, in queries per
+	// second. Default is 100.
+	AuditQPS float64
+
+	// AuditWriter is where the writes the audit didn't find are written as
+	// csv rows, with the hash and height of their tx. Optional.
+	AuditWriter io.Writer
+
 	// Writer is where results will be written. If nil, results are written to stdout.
 	Writer io.Writer
 
+	// Interval is the time between snapshots of throughput, error rate and
+	// latency taken during the run. Default is 1s.
+	Interval time.Duration
+
+	// LiveWriter is where each interval snapshot is printed as a row of a
+	// rolling table while the run is in progress. Optional.
+	LiveWriter io.Writer
+
+	// TimeSeriesWriter is where each interval snapshot is written as a csv
+	// row while the run is in progress. Optional.
+	TimeSeriesWriter io.Writer
+
+	// MetricsAddr is the address to serve Prometheus metrics on while the
+	// run is in progress, in the format of "host:port". Optional.
+	MetricsAddr string
+
+	// MetricsLinger is how long Run keeps serving the metrics once the run
+	// is over, so that a scraper can collect the final values. Stop ends
+	// it early.
+	MetricsLinger time.Duration
+
+	// ResultLog is where every result is logged as it arrives, including
+	// warmup requests and errors. Optional.
+	ResultLog io.Writer
+
+	// ResultLogFormat is the format of ResultLog, either "csv" or "ndjson".
+	// Default is "csv".
+	ResultLogFormat string
+
+	// OnResult, if non-nil, is called with every result as it arrives,
+	// from the goroutine collecting the results. Optional.
+	OnResult func(Result)
+
+	// quiet disables printing the summary, for Benchmark.
+	quiet bool
+
 	initOnce sync.Once
+	stopOnce sync.Once
+	errOnce  sync.Once
+	err      error
 	results  chan *result
 	stopCh   chan struct{}
 	start    time.Duration
+	issued   int64
+	runID    string
+	health   *loomclient.HealthChecker
+	events   nodeEvents
+	verifyCh chan verifyJob
 
 	// Progress tracking
 	UseProgress bool
 	Progress    chan struct{}
 
-	report *report
+	report  *report
+	metrics *metrics
 }
 
 func (b *Work) writer() io.Writer {
@@ -150,7 +331,7 @@ func (b *Work) writer() io.Writer {
 func (b *Work) Init() {
 	b.initOnce.Do(func() {
 		b.results = make(chan *result, min(b.C*1000, maxResult))
-		b.stopCh = make(chan struct{}, b.C)
+		b.stopCh = make(chan struct{})
 		if b.UseProgress {
 			b.Progress = make(chan struct{}, b.C*2)
 		}
@@ -158,81 +339,266 @@ func (b *Work) Init() {
 }
 
 // Run makes all the requests, prints the summary. It blocks until
-// all work is done.
-func (b *Work) Run() {
+// all work is done, ctx is done or the run is stopped. Requests in flight
+// when it stops are aborted and reported as cancelled.
+func (b *Work) Run(ctx context.Context) {
 	b.Init()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-b.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	info := b.runInfo()
+	if b.Output == "json" || b.Output == "html" {
+		info.Node = b.nodeInfo(ctx)
+	}
 	b.start = now()
-	b.report = newReport(b.writer(), b.results, b.Output, b.N)
+	info.StartTime = time.Now()
+	b.runID = strconv.FormatInt(info.StartTime.UnixNano(), 36)
+	if b.MetricsAddr != "" {
+		m := newMetrics()
+		if err := m.serve(b.MetricsAddr); err != nil {
+			b.fail(err)
+			return
+		}
+		m.targetRate.Set(b.QPS * float64(b.C))
+		b.metrics = m
+		defer m.linger(ctx, b.MetricsLinger)
+	}
+	if err := b.startProfiles(); err != nil {
+		b.fail(err)
+		return
+	}
+	b.report = newReport(b.writer(), b.results, b.Output)
+	b.report.info = info
+	b.report.metrics = b.metrics
+	b.report.start = b.start
+	b.report.interval = b.interval()
+	b.report.quiet = b.quiet
+	if b.Verify {
+		b.report.verify = &verifyStats{staleness: newHDRHistogram()}
+	}
+	b.report.auditing = b.Audit
+	if b.Retry != nil && b.Retry.MaxAttempts > 1 {
+		b.report.retry = &retryStats{lats: newHDRHistogram()}
+	}
+	if b.batchSize() > 1 {
+		b.report.batch = &batchStats{lats: newHDRHistogram()}
+	}
+	if len(b.nodes()) > 1 {
+		b.report.nodes = make(map[string]*opStats)
+	}
+	b.report.onResult = b.OnResult
+	overheadCtx, stopOverhead := context.WithCancel(ctx)
+	b.report.overhead = startOverhead(overheadCtx)
+	if b.LiveWriter != nil {
+		b.report.live = &liveTable{w: b.LiveWriter}
+	}
+	if b.TimeSeriesWriter != nil {
+		b.report.timeSeries = &timeSeriesCSV{w: b.TimeSeriesWriter}
+	}
+	if b.ResultLog != nil {
+		b.report.log = newResultLog(b.ResultLog, b.ResultLogFormat)
+	} else if b.Output == "csv" {
+		b.report.log = newResultLog(b.writer(), ResultLogCSV)
+	}
+	if b.report.log != nil {
+		b.report.log.startTime = info.StartTime
+		b.report.log.start = b.start
+	}
 	// Run the reporter first, it polls the result channel until it is closed.
 	go func() {
 		runReporter(b.report)
 	}()
-	b.runWorkers()
-	b.Finish()
+	runCtx := ctx
+	if b.Duration > 0 {
+		var cancelDuration context.CancelFunc
+		runCtx, cancelDuration = context.WithTimeout(ctx, b.Duration)
+		defer cancelDuration()
+	}
+	waitVerify := b.startVerifiers(ctx)
+	if b.HealthCheck > 0 {
+		healthCtx, stopHealth := context.WithCancel(runCtx)
+		b.startHealthCheck(healthCtx)
+		b.runWorkers(runCtx)
+		stopHealth()
+	} else {
+		b.runWorkers(runCtx)
+	}
+	waitVerify()
+	stopOverhead()
+	b.stopCPUProfile()
+	b.finish(ctx)
+	if err := b.writeHeapProfile(); err != nil {
+		b.fail(err)
+	}
 }
 
+// Stop stops the run, aborting the requests in flight.
 func (b *Work) Stop() {
-	// Send stop signal so that workers can stop gracefully.
-	for i := 0; i < b.C; i++ {
-		b.stopCh <- struct{}{}
-	}
+	b.Init()
+	b.stopOnce.Do(func() {
+		close(b.stopCh)
+	})
 }
 
 func (b *Work) Finish() {
+	b.finish(context.Background())
+}
+
+func (b *Work) finish(ctx context.Context) {
 	close(b.results)
-	total := now() - b.start
+	end := now()
 	// Wait until the reporter is done.
 	<-b.report.done
-	b.report.finalize(total)
+	b.report.info.EndTime = time.Now()
+	b.report.events = b.events.list()
+	if b.report.overhead != nil {
+		b.report.overhead.stop()
+	}
+	if b.Audit {
+		audit, err := b.audit(ctx, b.report.written)
+		if err != nil {
+			b.fail(err)
+		}
+		b.report.audit = audit
+	}
+	b.report.finalize(b.start, end)
+	if b.report.err != nil {
+		b.fail(b.report.err)
+	}
+}
+
+// fail stops the run because of err, unless it was already stopped.
+func (b *Work) fail(err error) {
+	b.errOnce.Do(func() {
+		b.err = err
+	})
+	b.Stop()
+}
+
+// Err returns the error that stopped a finished run, if a worker couldn't be
+// set up, or that the run ended with, such as an error writing the
+// per-request log or the report, or auditing the writes.
+func (b *Work) Err() error {
+	return b.err
+}
+
+// Report returns the summary of a finished run.
+func (b *Work) Report() Report {
+	if b.report == nil {
+		return Report{}
+	}
+	return b.report.snapshot()
+}
+
+func (b *Work) interval() time.Duration {
+	if b.Interval <= 0 {
+		return defaultInterval
+	}
+	return b.Interval
+}
+
+func (b *Work) workload() string {
+	if b.TransactionType == "" {
+		return WorkloadCall
+	}
+	return b.TransactionType
+}
+
+func (b *Work) batchSize() int {
+	if b.UseRawRequest {
+		return 1
+	}
+	return b.BatchSize
+}
+
+func (b *Work) verifyTimeout() time.Duration {
+	if b.VerifyTimeout <= 0 {
+		return defaultVerifyTimeout
+	}
+	return b.VerifyTimeout
+}
+
+// isWarmup reports whether a request sent at s falls in the warmup period.
+func (b *Work) isWarmup(s time.Duration) bool {
+	return b.warmupN(s, 1) == 1
 }
 
-func (b *Work) makeRequest(lc *loomclient.ContractClient, rpc *loomclient.DAppChainRPCClient, nonce uint64) {
+// warmupN returns how many of k requests sent together at s, such as the
+// requests of a batch, fall in the warmup period: the first ones until
+// WarmupN requests were sent, or all of them during Warmup.
+func (b *Work) warmupN(s time.Duration, k int) int {
+	var n int64
+	if b.WarmupN > 0 {
+		issued := atomic.AddInt64(&b.issued, int64(k))
+		n = int64(b.WarmupN) - (issued - int64(k))
+	}
+	if n > int64(k) || b.Warmup > 0 && s-b.start < b.Warmup {
+		return k
+	}
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+func (b *Work) makeRequest(ctx context.Context, ws *WorkerState, op Op, tracer *callTracer, nonce uint64) {
 	s := now()
+	warmup := b.isWarmup(s)
+	if b.metrics != nil {
+		b.metrics.inFlight.Inc()
+	}
 	// var size int64
 	// var code int
-	var connStart, resStart, reqStart, delayStart time.Duration
-	var connDuration, resDuration, reqDuration, delayDuration time.Duration
 	// req := cloneRequest(b.Request, b.RequestBody)
-	trace := &httptrace.ClientTrace{
-		GetConn: func(h string) {
-			connStart = now()
-		},
-		GotConn: func(connInfo httptrace.GotConnInfo) {
-			if !connInfo.Reused {
-				connDuration = now() - connStart
-			}
-			reqStart = now()
-		},
-		WroteRequest: func(w httptrace.WroteRequestInfo) {
-			reqDuration = now() - reqStart
-			delayStart = now()
-		},
-		GotFirstResponseByte: func() {
-			delayDuration = now() - delayStart
-			resStart = now()
-		},
-	}
 
 	// TODO: Save request body from contract params and clone request on each call
 	// 		 to save time from client overhead.
-	// add traceclient to DAppChainRPCClient
-	rpc.UseTrace(trace)
 	// make Loom Call
-	var err error
+	lc := ws.Client
+	var call func() error
+	name := b.ContractMethod
 	if b.UseRawRequest {
-		var signedTxBytes, rpcReqBytes []byte
 		contract := lc.GetContract()
-		signedTxBytes, err = contract.SignTxBytes(b.RequestBodyRaw, nonce, lc.GetSigner())
-		if err != nil {
-			panic(err)
+		var rpcReqBytes []byte
+		signedTxBytes, craftErr := contract.SignTxBytes(b.RequestBodyRaw, nonce, lc.GetSigner())
+		if craftErr == nil {
+			rpcReqBytes, craftErr = contract.CraftRPCReqBytes("broadcast_tx_commit", signedTxBytes)
 		}
-		rpcReqBytes, err = contract.CraftRPCReqBytes("broadcast_tx_commit", signedTxBytes)
-		if err != nil {
-			panic(err)
+		call = func() error {
+			// A request that couldn't be crafted fails like any other.
+			if craftErr != nil {
+				return craftErr
+			}
+			return contract.CallRaw(ctx, rpcReqBytes)
 		}
-		err = contract.CallRaw(rpcReqBytes)
 	} else {
-		err = lc.Call(b.ContractMethod, b.RequestBody, nil)
+		name = op.Name()
+		call = func() error {
+			return op.Execute(ctx, ws)
+		}
+	}
+	attempts, err := b.call(ctx, op, call)
+	category := classify(op, err)
+	var failedOver bool
+	if b.Failover && op != nil && canFailover(category) && ctx.Err() == nil {
+		if n := b.failoverNode(ws); n != nil {
+			ws.use(n)
+			failedOver = true
+			attempts++
+			err = op.Execute(ctx, ws)
+			category = classify(op, err)
+		}
+	}
+	var account string
+	if b.ResultLog != nil || b.OnResult != nil {
+		account = lc.GetCallerAddress().String()
 	}
 
 	// req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
@@ -244,19 +610,58 @@ func (b *Work) makeRequest(lc *loomclient.ContractClient, rpc *loomclient.DAppCh
 	// 	resp.Body.Close()
 	// }
 	t := now()
-	resDuration = t - resStart
+	if b.metrics != nil {
+		b.metrics.inFlight.Dec()
+	}
+	phases, aux := tracer.take()
+	var written Verifiable
+	if err == nil {
+		if v, ok := op.(Verifiable); ok {
+			if b.Verify && !warmup {
+				b.queueVerify(ctx, ws, v, t)
+			}
+			if b.Audit {
+				written = v
+			}
+		}
+	}
+	var commit loomclient.CommitInfo
+	if phases.method == "broadcast_tx_commit" {
+		commit = ws.RPC.LastCommit()
+		if b.UseRawRequest {
+			commit.Nonce = nonce
+		}
+	}
 	finish := t - s
 	b.results <- &result{
 		// statusCode:    code,
 		statusCode:    200, // TODO: Get stausCoec from Loom Call
 		duration:      finish,
 		err:           err,
+		category:      category,
+		cancelled:     err != nil && ctx.Err() != nil,
+		op:            name,
+		node:          ws.Node,
+		failedOver:    failedOver,
+		attempts:      attempts,
+		start:         s,
+		warmup:        warmup,
 		contentLength: 0, // TODO: Get ContentLength from Loom Call
-		connDuration:  connDuration,
-		// dnsDuration:   dnsDuration,
-		reqDuration:   reqDuration,
-		resDuration:   resDuration,
-		delayDuration: delayDuration,
+		connDuration:  phases.connDuration,
+		dnsDuration:   phases.dnsDuration,
+		dialDuration:  phases.dialDuration,
+		tlsDuration:   phases.tlsDuration,
+		reqDuration:   phases.reqDuration,
+		resDuration:   phases.resDuration,
+		delayDuration: phases.delayDuration,
+		gotConn:       phases.gotConn,
+		connReused:    phases.connReused,
+		aux:           aux,
+		written:       written,
+		account:       account,
+		nonce:         commit.Nonce,
+		txHash:        commit.Hash,
+		height:        commit.Height,
 	}
 
 	if b.UseProgress {
@@ -266,21 +671,76 @@ func (b *Work) makeRequest(lc *loomclient.ContractClient, rpc *loomclient.DAppCh
 	// return err
 }
 
-func (b *Work) runWorker(client *http.Client, n int) {
+// call makes a request of op with call, retrying it as the Retry policy
+// says of the errors classify returns for op. It returns the number of
+// attempts made and the error of the last one.
+func (b *Work) call(ctx context.Context, op Op, call func() error) (int, error) {
+	if b.Retry == nil {
+		return 1, call()
+	}
+	return b.Retry.Do(ctx, call, func(err error) string { return classify(op, err) })
+}
+
+// classify returns the category of err, the error of op.
+func classify(op Op, err error) string {
+	if err == nil {
+		return ""
+	}
+	if c, ok := op.(Classifier); ok {
+		if category := c.Classify(err); category != "" {
+			return category
+		}
+	}
+	return loomclient.ErrorCategory(err)
+}
+
+func (b *Work) runWorker(ctx context.Context, client *http.Client, id, n int) {
 	var throttle <-chan time.Time
 	if b.QPS > 0 {
 		throttle = time.Tick(time.Duration(1e6/(b.QPS)) * time.Microsecond)
 	}
 
-	if b.DisableRedirects {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
+	tracer := &callTracer{}
+	nodes, err := b.createWorkerClients(ctx, client, tracer) // Create Loom Client
+	if ctx.Err() != nil {
+		// The run was stopped while the worker was setting up.
+		return
 	}
-
-	lc, rpc, err := b.createWorkerClients(client) // Create Loom Client
 	if err != nil {
-		panic(err)
+		b.fail(err)
+		return
+	}
+
+	ws := &WorkerState{
+		ID:    id,
+		RunID: b.runID,
+		Rand:  rand.New(rand.NewSource(time.Now().UnixNano() + int64(id))),
+		nodes: nodes,
+	}
+	sticky := stickyNode(nodes[0].client.GetCallerAddress().String(), len(nodes))
+	if b.Balance == BalanceSticky {
+		ws.use(nodes[sticky])
+	} else {
+		ws.use(nodes[id%len(nodes)])
+	}
+	lc, rpc := ws.Client, ws.RPC
+	var workload Workload
+	if !b.UseRawRequest {
+		workload, err = newWorkload(b.workload(), WorkloadConfig{
+			ContractMethod: b.ContractMethod,
+			RequestBody:    b.RequestBody,
+			Ratio:          b.Ratio,
+		})
+		if err == nil {
+			err = workload.Setup(ctx, ws)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			b.fail(err)
+			return
+		}
 	}
 
 	nonce := uint64(0)
@@ -289,61 +749,81 @@ func (b *Work) runWorker(client *http.Client, n int) {
 		signer := lc.GetSigner()
 		b.RequestBodyRaw, err = lc.GetContract().CraftCallTx(b.ContractMethod, b.RequestBody, signer)
 		if err != nil {
-			panic(err)
+			b.fail(err)
+			return
+		}
+		nonce, err = rpc.GetNonce(ctx, signer)
+		if ctx.Err() != nil {
+			return
 		}
-		nonce, err = rpc.GetNonce(signer)
 		if err != nil {
-			panic(err)
+			b.fail(err)
+			return
 		}
 	}
+	// Drop the calls made to set up, such as resolving the contract and
+	// fetching the nonce, so they aren't charged to the first request.
+	tracer.take()
+
+	if workload != nil && b.batchSize() > 1 {
+		b.runBatches(ctx, ws, workload, tracer, throttle, sticky, n)
+		return
+	}
 
 	for i := 0; i < n; i++ {
 		// Check if application is stopped. Do not send into a closed channel.
 		select {
-		case <-b.stopCh:
+		case <-ctx.Done():
 			return
 		default:
 			if b.QPS > 0 {
-				<-throttle
+				select {
+				case <-throttle:
+				case <-ctx.Done():
+					return
+				}
+			}
+			ws.use(b.pickNode(ws, nodes, sticky))
+			var op Op
+			if workload != nil {
+				op = workload.Next(ws)
 			}
-			b.makeRequest(lc, rpc, nonce)
+			b.makeRequest(ctx, ws, op, tracer, nonce)
+			ws.Seq++
 			nonce++
 		}
 	}
 }
 
-func (b *Work) runWorkers() {
+func (b *Work) runWorkers(ctx context.Context) {
+	transport, err := b.transport()
+	if err != nil {
+		b.fail(err)
+		return
+	}
 	var wg sync.WaitGroup
 	wg.Add(b.C)
 
-	tr := &http.Transport{
-		// TLSClientConfig: &tls.Config{
-		// 	InsecureSkipVerify: true,
-		// 	ServerName:         b.Request.Host,
-		// },
-		MaxIdleConnsPerHost: min(b.C, maxIdleConn),
-		DisableCompression:  b.DisableCompression,
-		DisableKeepAlives:   b.DisableKeepAlives,
-		Proxy:               http.ProxyURL(b.ProxyAddr),
-	}
-	if b.H2 {
-		http2.ConfigureTransport(tr)
-	} else {
-		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	httpclient := &http.Client{Transport: transport, Timeout: time.Duration(b.Timeout) * time.Second}
+	if b.DisableRedirects {
+		httpclient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
-	httpclient := &http.Client{Transport: tr, Timeout: time.Duration(b.Timeout) * time.Second}
 
 	// Ignore the case where b.N % b.C != 0.
 	for i := 0; i < b.C; i++ {
-		go func() {
-			b.runWorker(httpclient, b.N/b.C)
+		go func(id int) {
+			b.runWorker(ctx, httpclient, id, b.N/b.C)
 			wg.Done()
-		}()
+		}(i)
 	}
 	wg.Wait()
 }
 
-func (b *Work) createWorkerClients(httpclient *http.Client) (*loomclient.ContractClient, *loomclient.DAppChainRPCClient, error) {
+// createWorkerClients returns the clients of a worker for every node, all
+// sending txs from the same account.
+func (b *Work) createWorkerClients(ctx context.Context, httpclient *http.Client, tracer *callTracer) ([]*workerNode, error) {
 	// create signer
 	// var signer *auth.Signer
 	var privKey []byte
@@ -353,23 +833,49 @@ func (b *Work) createWorkerClients(httpclient *http.Client) (*loomclient.Contrac
 	} else {
 		privKeyB64, err := ioutil.ReadFile(b.PrivateKey)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		privKey, err = base64.StdEncoding.DecodeString(string(privKeyB64))
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	signer := auth.NewEd25519Signer(privKey)
 
-	rpcClient := loomclient.NewDAppChainRPCClient(httpclient, b.ChainID, b.WriteURL, b.ReadURL)
-	client, err := loomclient.NewContractClient(b.ContractAddress, b.ChainID, signer, rpcClient)
+	var nodes []*workerNode
+	for _, n := range b.nodes() {
+		rpcClient := loomclient.NewDAppChainRPCClient(httpclient, b.ChainID, n.WriteURL, n.ReadURL)
+		rpcClient.UseTracer(tracer.trace)
+		rpcClient.UseFastJSON(b.UseFastJSON)
+		client, err := loomclient.NewContractClient(ctx, b.ContractAddress, b.ChainID, signer, rpcClient)
+		if err != nil {
+			return nil, err
+		}
+		wn := &workerNode{name: n.Name, client: client, rpc: rpcClient}
+		if b.Verify {
+			wn.verify, err = b.createVerifyClient(ctx, httpclient, signer, n)
+			if err != nil {
+				return nil, err
+			}
+		}
+		nodes = append(nodes, wn)
+	}
+	return nodes, nil
+}
 
-	return client, rpcClient, err
+// createVerifyClient returns an untraced client to read back the writes
+// sent to node.
+func (b *Work) createVerifyClient(ctx context.Context, httpclient *http.Client, signer auth.Signer, node Node) (*loomclient.ContractClient, error) {
+	readURL := b.VerifyURL
+	if readURL == "" {
+		readURL = node.ReadURL
+	}
+	rpcClient := loomclient.NewDAppChainRPCClient(httpclient, b.ChainID, node.WriteURL, readURL)
+	return loomclient.NewContractClient(ctx, b.ContractAddress, b.ChainID, signer, rpcClient)
 }
 
 // cloneRequest returns a clone of the provided *http.Request.
@@ -0,0 +1,153 @@
+package requester
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats of the per-request log.
+const (
+	ResultLogCSV    = "csv"
+	ResultLogNDJSON = "ndjson"
+)
+
+var resultLogHeader = []string{
+	"time", "offset", "op", "warmup", "result", "category", "duration",
+	"DNS+dialup", "DNS", "dial", "TLS", "request-write", "response-delay",
+	"response-read", "aux", "conn-reused", "status-code", "account", "nonce",
+	"tx-hash", "height", "error", "node",
+}
+
+// resultLogEntry is a line of the ndjson per-request log. Durations are in
+// seconds, aux is the total duration of auxiliary calls such as nonce
+// lookups.
+type resultLogEntry struct {
+	Time          time.Time `json:"time"`
+	Offset        float64   `json:"offset"`
+	Op            string    `json:"op"`
+	Warmup        bool      `json:"warmup"`
+	Result        string    `json:"result"`
+	Category      string    `json:"category,omitempty"`
+	Duration      float64   `json:"duration"`
+	ConnDuration  float64   `json:"conn"`
+	DNSDuration   float64   `json:"dns"`
+	DialDuration  float64   `json:"dial"`
+	TLSDuration   float64   `json:"tls"`
+	ReqDuration   float64   `json:"req"`
+	DelayDuration float64   `json:"delay"`
+	ResDuration   float64   `json:"res"`
+	AuxDuration   float64   `json:"aux"`
+	ConnReused    bool      `json:"conn_reused"`
+	StatusCode    int       `json:"status_code"`
+	Account       string    `json:"account,omitempty"`
+	Nonce         uint64    `json:"nonce,omitempty"`
+	TxHash        string    `json:"tx_hash,omitempty"`
+	Height        int64     `json:"height,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Node          string    `json:"node,omitempty"`
+}
+
+// resultLog streams every result, including warmup requests and errors, as
+// it arrives.
+type resultLog struct {
+	w      *bufio.Writer
+	format string
+	csv    *csv.Writer
+	json   *json.Encoder
+
+	// Wall clock and monotonic time of the start of the run, to timestamp
+	// results.
+	startTime time.Time
+	start     time.Duration
+}
+
+func newResultLog(w io.Writer, format string) *resultLog {
+	l := &resultLog{w: bufio.NewWriter(w), format: format}
+	if format == ResultLogNDJSON {
+		l.json = json.NewEncoder(l.w)
+	} else {
+		l.csv = csv.NewWriter(l.w)
+		l.csv.Write(resultLogHeader)
+	}
+	return l
+}
+
+func (l *resultLog) write(res *result) error {
+	e := resultLogEntry{
+		Time:          l.startTime.Add(res.start - l.start),
+		Offset:        (res.start - l.start).Seconds(),
+		Op:            res.op,
+		Warmup:        res.warmup,
+		Result:        "success",
+		Duration:      res.duration.Seconds(),
+		ConnDuration:  res.connDuration.Seconds(),
+		DNSDuration:   res.dnsDuration.Seconds(),
+		DialDuration:  res.dialDuration.Seconds(),
+		TLSDuration:   res.tlsDuration.Seconds(),
+		ReqDuration:   res.reqDuration.Seconds(),
+		DelayDuration: res.delayDuration.Seconds(),
+		ResDuration:   res.resDuration.Seconds(),
+		ConnReused:    res.connReused,
+		StatusCode:    res.statusCode,
+		Account:       res.account,
+		Nonce:         res.nonce,
+		TxHash:        res.txHash,
+		Height:        res.height,
+		Node:          res.node,
+	}
+	for _, a := range res.aux {
+		e.AuxDuration += a.duration.Seconds()
+	}
+	if res.err != nil {
+		e.Result = "error"
+		e.Category = res.category
+		e.Error = res.err.Error()
+	}
+	if l.json != nil {
+		return l.json.Encode(e)
+	}
+	return l.csv.Write([]string{
+		e.Time.Format(time.RFC3339Nano),
+		formatSecs(e.Offset),
+		e.Op,
+		strconv.FormatBool(e.Warmup),
+		e.Result,
+		e.Category,
+		formatSecs(e.Duration),
+		formatSecs(e.ConnDuration),
+		formatSecs(e.DNSDuration),
+		formatSecs(e.DialDuration),
+		formatSecs(e.TLSDuration),
+		formatSecs(e.ReqDuration),
+		formatSecs(e.DelayDuration),
+		formatSecs(e.ResDuration),
+		formatSecs(e.AuxDuration),
+		strconv.FormatBool(e.ConnReused),
+		strconv.Itoa(e.StatusCode),
+		e.Account,
+		strconv.FormatUint(e.Nonce, 10),
+		e.TxHash,
+		strconv.FormatInt(e.Height, 10),
+		e.Error,
+		e.Node,
+	})
+}
+
+// flush writes buffered entries to the underlying writer.
+func (l *resultLog) flush() error {
+	if l.csv != nil {
+		l.csv.Flush()
+		if err := l.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return l.w.Flush()
+}
+
+func formatSecs(secs float64) string {
+	return strconv.FormatFloat(secs, 'f', 6, 64)
+}
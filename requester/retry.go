@@ -0,0 +1,58 @@
+package requester
+
+// retryStats aggregates the attempts of requests made with a retry policy.
+type retryStats struct {
+	firstAttempt int64
+	afterRetry   int64
+	failed       int64
+	retries      int64
+	lats         *hdrHistogram // latency of the requests that needed a retry
+}
+
+func (r *retryStats) add(res *result) {
+	if res.attempts > 1 {
+		r.retries += int64(res.attempts - 1)
+	}
+	switch {
+	case res.err != nil:
+		r.failed++
+	case res.attempts > 1:
+		r.afterRetry++
+		r.lats.record(res.duration)
+	default:
+		r.firstAttempt++
+	}
+}
+
+func (r *retryStats) report() *RetryReport {
+	return &RetryReport{
+		FirstAttempt:        r.firstAttempt,
+		AfterRetry:          r.afterRetry,
+		Failed:              r.failed,
+		Retries:             r.retries,
+		Average:             r.lats.mean(),
+		Slowest:             r.lats.max.Seconds(),
+		LatencyDistribution: r.lats.distribution(pctls),
+	}
+}
+
+// RetryReport summarizes the attempts of the requests of a run made with a
+// retry policy.
+type RetryReport struct {
+	// FirstAttempt is the number of requests that succeeded at the first
+	// attempt, AfterRetry the number that succeeded after one or more
+	// retries and Failed the number whose last attempt failed.
+	FirstAttempt int64 `json:"first_attempt"`
+	AfterRetry   int64 `json:"after_retry"`
+	Failed       int64 `json:"failed"`
+
+	// Retries is the number of attempts made after the first ones.
+	Retries int64 `json:"retries"`
+
+	// Average, Slowest and LatencyDistribution describe the latency of the
+	// requests that succeeded after a retry, including every attempt and
+	// the backoff between them, in seconds.
+	Average             float64               `json:"average"`
+	Slowest             float64               `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+}
@@ -0,0 +1,161 @@
+package requester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jsimnz/loombench/loomclient"
+	"github.com/jsimnz/loombench/types"
+)
+
+// Built-in workloads.
+const (
+	// WorkloadCall calls the -m method with the request body, the same
+	// for every request.
+	WorkloadCall = "call"
+
+	// WorkloadWrite, WorkloadRead and WorkloadMixed exercise the
+	// SimpleStore contract: writes Set unique keys, reads Get keys the
+	// worker wrote.
+	WorkloadWrite = "write"
+	WorkloadRead  = "read"
+	WorkloadMixed = "mixed"
+)
+
+// maxReadKeys is the number of the last written keys a worker reads from.
+const maxReadKeys = 1024
+
+func init() {
+	RegisterWorkload(WorkloadCall, func(cfg WorkloadConfig) (Workload, error) {
+		return &callWorkload{op: &callOp{method: cfg.ContractMethod, body: cfg.RequestBody}}, nil
+	})
+	RegisterWorkload(WorkloadWrite, func(cfg WorkloadConfig) (Workload, error) {
+		return &storeWorkload{ratio: 0}, nil
+	})
+	RegisterWorkload(WorkloadRead, func(cfg WorkloadConfig) (Workload, error) {
+		return &storeWorkload{ratio: 1}, nil
+	})
+	RegisterWorkload(WorkloadMixed, func(cfg WorkloadConfig) (Workload, error) {
+		if cfg.Ratio < 0 || cfg.Ratio > 1 {
+			return nil, fmt.Errorf("requester: read ratio %v is not between 0 and 1", cfg.Ratio)
+		}
+		return &storeWorkload{ratio: cfg.Ratio}, nil
+	})
+}
+
+type callWorkload struct {
+	op *callOp
+}
+
+func (l *callWorkload) Setup(ctx context.Context, w *WorkerState) error { return nil }
+
+func (l *callWorkload) Next(w *WorkerState) Op { return l.op }
+
+type callOp struct {
+	method string
+	body   proto.Message
+}
+
+func (o *callOp) Name() string { return o.method }
+
+func (o *callOp) Execute(ctx context.Context, w *WorkerState) error {
+	return w.Client.Call(ctx, o.method, o.body, nil)
+}
+
+func (o *callOp) Call() (*loomclient.ContractCall, bool) {
+	return &loomclient.ContractCall{Method: o.method, Args: o.body}, true
+}
+
+// storeWorkload sets and gets SimpleStore keys, a ratio of the operations
+// being gets.
+type storeWorkload struct {
+	ratio float64
+
+	// keys is a ring of the last keys written, next the index of the
+	// oldest once it is full.
+	keys [][]byte
+	next int
+
+	// written is the number of keys written.
+	written uint64
+}
+
+// Setup writes a key, so that there is one to read from the start.
+func (l *storeWorkload) Setup(ctx context.Context, w *WorkerState) error {
+	if l.ratio == 0 {
+		return nil
+	}
+	op := l.set(w)
+	if err := op.Execute(ctx, w); err != nil {
+		return fmt.Errorf("writing the first key: %v", err)
+	}
+	return nil
+}
+
+func (l *storeWorkload) Next(w *WorkerState) Op {
+	if l.ratio > 0 && w.Rand.Float64() < l.ratio {
+		return &getOp{key: l.keys[w.Rand.Intn(len(l.keys))]}
+	}
+	return l.set(w)
+}
+
+// set returns the write of a new key, and adds it to the keys read from.
+func (l *storeWorkload) set(w *WorkerState) *setOp {
+	key := []byte(fmt.Sprintf("loombench-%s-%d-%d", w.RunID, w.ID, l.written))
+	l.written++
+	val := []byte(fmt.Sprintf("%016x", w.Rand.Int63()))
+	if l.ratio > 0 {
+		if len(l.keys) < maxReadKeys {
+			l.keys = append(l.keys, key)
+		} else {
+			l.keys[l.next] = key
+			l.next = (l.next + 1) % maxReadKeys
+		}
+	}
+	return &setOp{key: key, val: val}
+}
+
+type setOp struct {
+	key, val []byte
+}
+
+func (o *setOp) Name() string { return "Set" }
+
+func (o *setOp) Execute(ctx context.Context, w *WorkerState) error {
+	return w.Client.Call(ctx, "Set", &types.LoomBenchWriteTx{Key: o.key, Val: o.val}, nil)
+}
+
+func (o *setOp) Call() (*loomclient.ContractCall, bool) {
+	return &loomclient.ContractCall{Method: "Set", Args: &types.LoomBenchWriteTx{Key: o.key, Val: o.val}}, true
+}
+
+func (o *setOp) Key() string { return string(o.key) }
+
+// ReadBack gets the key of the write.
+func (o *setOp) ReadBack(ctx context.Context, c *loomclient.ContractClient) (visible, match bool, err error) {
+	var resp types.LoomBenchResp
+	if err := c.StaticCall(ctx, "Get", &types.LoomBenchReadTx{Key: o.key}, &resp); err != nil {
+		return false, false, err
+	}
+	if len(resp.Val) == 0 {
+		return false, false, nil
+	}
+	return true, bytes.Equal(resp.Val, o.val), nil
+}
+
+type getOp struct {
+	key []byte
+}
+
+func (o *getOp) Name() string { return "Get" }
+
+func (o *getOp) Execute(ctx context.Context, w *WorkerState) error {
+	var resp types.LoomBenchResp
+	return w.Client.StaticCall(ctx, "Get", &types.LoomBenchReadTx{Key: o.key}, &resp)
+}
+
+func (o *getOp) Call() (*loomclient.ContractCall, bool) {
+	return &loomclient.ContractCall{Method: "Get", Args: &types.LoomBenchReadTx{Key: o.key}, Result: &types.LoomBenchResp{}}, false
+}
@@ -0,0 +1,109 @@
+package requester
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Default time between snapshots of the time series.
+const defaultInterval = time.Second
+
+// Number of rows between repeated headers of the live table.
+const liveHeaderEvery = 20
+
+// Interval is a snapshot of the results that completed within one interval
+// of the run. Intervals include warmup requests.
+type Interval struct {
+	// Start of the interval, relative to the start of the run.
+	Start    time.Duration `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	NumRes    int64   `json:"num_res"`
+	Errors    int64   `json:"errors"`
+	Rps       float64 `json:"rps"`
+	ErrorRate float64 `json:"error_rate"`
+
+	Average float64 `json:"average"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+	Slowest float64 `json:"slowest"`
+}
+
+// window accumulates the results of the interval in progress.
+type window struct {
+	start  time.Duration
+	numRes int64
+	errors int64
+	lats   *hdrHistogram
+}
+
+func (w *window) add(res *result) {
+	w.numRes++
+	if res.err != nil {
+		w.errors++
+		return
+	}
+	w.lats.record(res.duration)
+}
+
+// flush returns the snapshot of the window up to end and resets it.
+func (w *window) flush(end time.Duration) Interval {
+	iv := Interval{
+		Start:    w.start,
+		Duration: end - w.start,
+		NumRes:   w.numRes,
+		Errors:   w.errors,
+		Average:  w.lats.mean(),
+		P50:      w.lats.quantile(50),
+		P90:      w.lats.quantile(90),
+		P99:      w.lats.quantile(99),
+		Slowest:  w.lats.max.Seconds(),
+	}
+	if iv.Duration > 0 {
+		iv.Rps = float64(w.numRes) / iv.Duration.Seconds()
+	}
+	if w.numRes > 0 {
+		iv.ErrorRate = float64(w.errors) / float64(w.numRes)
+	}
+
+	w.start = end
+	w.numRes = 0
+	w.errors = 0
+	w.lats.reset()
+	return iv
+}
+
+// liveTable prints intervals as a rolling table.
+type liveTable struct {
+	w    io.Writer
+	rows int
+}
+
+func (t *liveTable) print(iv Interval) {
+	if t.rows%liveHeaderEvery == 0 {
+		fmt.Fprintf(t.w, "%8s %8s %10s %8s %8s %8s %8s %8s\n",
+			"time", "reqs", "req/s", "errors", "p50", "p90", "p99", "slowest")
+	}
+	t.rows++
+	elapsed := (iv.Start + iv.Duration).Seconds()
+	fmt.Fprintf(t.w, "%7.1fs %8d %10.2f %7.2f%% %8.4f %8.4f %8.4f %8.4f\n",
+		elapsed, iv.NumRes, iv.Rps, iv.ErrorRate*100, iv.P50, iv.P90, iv.P99, iv.Slowest)
+}
+
+// timeSeriesCSV streams intervals as csv rows.
+type timeSeriesCSV struct {
+	w      io.Writer
+	header bool
+}
+
+func (t *timeSeriesCSV) write(iv Interval) {
+	if !t.header {
+		fmt.Fprintln(t.w, "time,duration,requests,errors,rps,error-rate,average,p50,p90,p99,slowest")
+		t.header = true
+	}
+	fmt.Fprintf(t.w, "%4.4f,%4.4f,%d,%d,%4.4f,%4.4f,%4.4f,%4.4f,%4.4f,%4.4f,%4.4f\n",
+		iv.Start.Seconds(), iv.Duration.Seconds(), iv.NumRes, iv.Errors,
+		iv.Rps, iv.ErrorRate, iv.Average, iv.P50, iv.P90, iv.P99, iv.Slowest)
+}
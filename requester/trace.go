@@ -0,0 +1,153 @@
+package requester
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// phaseTimes holds the durations of the phases of one call.
+type phaseTimes struct {
+	connStart, dnsStart, dialStart, tlsStart, resStart, reqStart, delayStart time.Duration
+
+	connDuration  time.Duration
+	dnsDuration   time.Duration
+	dialDuration  time.Duration
+	tlsDuration   time.Duration
+	reqDuration   time.Duration
+	delayDuration time.Duration
+	resDuration   time.Duration
+
+	gotConn    bool
+	connReused bool
+
+	// method is the RPC method of the call.
+	method string
+}
+
+// clientTrace returns the trace recording the phases of a call. Its hooks
+// lock mu, since HTTP/2 calls them from several goroutines.
+func (p *phaseTimes) clientTrace(mu *sync.Mutex) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(h string) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.connStart = now()
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.dnsStart = now()
+		},
+		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.dnsDuration = now() - p.dnsStart
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.dialStart = now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.dialDuration = now() - p.dialStart
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			p.tlsStart = now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.tlsDuration = now() - p.tlsStart
+		},
+		GotConn: func(connInfo httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.gotConn = true
+			p.connReused = connInfo.Reused
+			if !connInfo.Reused {
+				p.connDuration = now() - p.connStart
+			}
+			p.reqStart = now()
+		},
+		WroteRequest: func(w httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.reqDuration = now() - p.reqStart
+			p.delayStart = now()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			p.delayDuration = now() - p.delayStart
+			p.resStart = now()
+		},
+	}
+}
+
+// auxCall is a call made on behalf of a request besides the call that
+// performs its operation, such as the nonce lookup of a tx.
+type auxCall struct {
+	method   string
+	duration time.Duration
+	err      bool
+}
+
+// isMainCall reports whether a call to method performs the operation of a
+// request rather than looking up what it needs.
+func isMainCall(method string) bool {
+	return method == "broadcast_tx_commit" || method == "query"
+}
+
+// callTracer traces the calls of a worker's client. A worker makes one
+// request at a time, so the calls since the last request belong to the next
+// one.
+type callTracer struct {
+	mu   sync.Mutex // guards the phases of main
+	main *phaseTimes
+	aux  []auxCall
+}
+
+func (t *callTracer) trace(endpoint, method string) *loomclient.CallTrace {
+	if isMainCall(method) {
+		p := &phaseTimes{method: method}
+		t.main = p
+		return &loomclient.CallTrace{
+			ClientTrace: p.clientTrace(&t.mu),
+			Done: func(err error) {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				if p.resStart > 0 {
+					p.resDuration = now() - p.resStart
+				}
+			},
+		}
+	}
+	start := now()
+	return &loomclient.CallTrace{
+		Done: func(err error) {
+			t.aux = append(t.aux, auxCall{method: method, duration: now() - start, err: err != nil})
+		},
+	}
+}
+
+// take returns the phases of the last main call and the auxiliary calls made
+// since the last take, and resets them.
+func (t *callTracer) take() (*phaseTimes, []auxCall) {
+	main := &phaseTimes{}
+	if t.main != nil {
+		t.mu.Lock()
+		*main = *t.main
+		t.mu.Unlock()
+	}
+	aux := t.aux
+	t.main, t.aux = nil, nil
+	return main, aux
+}
@@ -0,0 +1,66 @@
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/jsimnz/loombench/loomclient"
+	"golang.org/x/net/http2"
+)
+
+// transport returns the transport the workers send their requests with.
+func (b *Work) transport() (http.RoundTripper, error) {
+	maxIdle := b.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = min(b.C, maxIdleConn)
+	}
+	tr := &http.Transport{
+		DialContext:     loomclient.DialEndpoint,
+		TLSClientConfig: b.TLSConfig,
+		// The total limit leaves room for maxIdle connections to both
+		// endpoints of every node.
+		MaxIdleConns:        maxIdle * 2 * len(b.nodes()),
+		MaxIdleConnsPerHost: maxIdle,
+		MaxConnsPerHost:     b.MaxConnsPerHost,
+		DisableCompression:  b.DisableCompression,
+		DisableKeepAlives:   b.DisableKeepAlives,
+		Proxy:               http.ProxyURL(b.ProxyAddr),
+	}
+	if !b.H2 {
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		return tr, nil
+	}
+	if err := http2.ConfigureTransport(tr); err != nil {
+		return nil, err
+	}
+	return &h2Transport{
+		tls: tr,
+		h2c: &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: b.DisableCompression,
+			// Dial without TLS, for the prior knowledge HTTP/2 of h2c. ctx
+			// is the context of the request, which aborts the dial and
+			// traces its phases.
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return loomclient.DialEndpoint(ctx, network, addr)
+			},
+		},
+	}, nil
+}
+
+// h2Transport sends HTTP/2 requests, negotiated with TLS to https nodes and
+// with prior knowledge (h2c) to the others, such as nodes behind a
+// gRPC-gateway style proxy.
+type h2Transport struct {
+	tls *http.Transport
+	h2c *http2.Transport
+}
+
+func (t *h2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "https" {
+		return t.tls.RoundTrip(req)
+	}
+	return t.h2c.RoundTrip(req)
+}
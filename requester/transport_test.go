@@ -0,0 +1,77 @@
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestH2CTransport(t *testing.T) {
+	srv := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer srv.Close()
+
+	b := &Work{H2: true, C: 1}
+	tr, err := b.transport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var connectStart, connectDone bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) { connectStart = true },
+		ConnectDone:  func(network, addr string, err error) { connectDone = true },
+	})
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := tr.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("response protocol = %s, want HTTP/2", resp.Proto)
+	}
+	// The dial is made with the context of the request, which traces it.
+	if !connectStart || !connectDone {
+		t.Errorf("connect traced = %v, %v, want true, true", connectStart, connectDone)
+	}
+
+	// A cancelled request isn't dialed.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ = http.NewRequest("GET", "http://127.0.0.1:1", nil)
+	if _, err := tr.RoundTrip(req.WithContext(cancelled)); err == nil {
+		t.Error("cancelled h2c request succeeded")
+	}
+}
+
+func TestNewH2Options(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy:3128")
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"proxy", Config{H2: true, Proxy: proxy}},
+		{"disable keep-alive", Config{H2: true, DisableKeepAlives: true}},
+		{"max conns per host", Config{H2: true, MaxConnsPerHost: 4}},
+	}
+	for _, tt := range tests {
+		tt.cfg.N = 1
+		if _, err := New(tt.cfg); err == nil {
+			t.Errorf("%s: New() accepted it with H2", tt.name)
+		}
+		tt.cfg.H2 = false
+		if _, err := New(tt.cfg); err != nil {
+			t.Errorf("%s: New() without H2: %v", tt.name, err)
+		}
+	}
+	if _, err := New(Config{N: 1, H2: true, MaxIdleConns: 4}); err != nil {
+		t.Errorf("New() with H2 and MaxIdleConns: %v", err)
+	}
+}
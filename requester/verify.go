@@ -0,0 +1,196 @@
+package requester
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// verifyPollInterval is the time between the reads of a write that isn't
+// visible yet.
+const verifyPollInterval = 20 * time.Millisecond
+
+// Default time a write has to become visible before it counts as lost.
+const defaultVerifyTimeout = 10 * time.Second
+
+// Per worker of a run, the number of goroutines verifying its writes, and
+// of writes queued for them before the worker waits for a free one.
+const (
+	verifiersPerWorker   = 4
+	verifyQueuePerWorker = 256
+)
+
+// Verifiable is implemented by ops whose writes can be read back, to verify
+// them with Work.Verify and audit them with Work.Audit. The SimpleStore
+// writes of the write and mixed workloads implement it.
+type Verifiable interface {
+	// Key identifies the state written by the op, such as the key it sets.
+	Key() string
+
+	// ReadBack reads the value written by the op with c. visible reports
+	// whether a value was found, match whether it is the one written. A
+	// write that isn't visible yet may return an error, such as a not
+	// found error from the contract.
+	ReadBack(ctx context.Context, c *loomclient.ContractClient) (visible, match bool, err error)
+}
+
+// Outcomes of the verification of a write.
+const (
+	verifyOK = iota
+	verifyLost
+	verifyMismatched
+)
+
+// verifyResult is the outcome of the verification of a write.
+type verifyResult struct {
+	outcome int
+
+	// staleness is the time from the end of the write to the start of the
+	// first read that saw it, or 0 if the first read did.
+	staleness time.Duration
+	reads     int
+}
+
+// verifyWrite reads back the write of op until the written value is
+// visible or timeout elapses. end is the time the write completed. It
+// returns nil if ctx is done before the outcome is known.
+func verifyWrite(ctx context.Context, c *loomclient.ContractClient, op Verifiable, end time.Duration, timeout time.Duration) *verifyResult {
+	res := &verifyResult{outcome: verifyLost}
+	deadline := end + timeout
+	for {
+		s := now()
+		visible, match, _ := op.ReadBack(ctx, c)
+		if ctx.Err() != nil {
+			return nil
+		}
+		res.reads++
+		if match {
+			if res.reads > 1 {
+				res.staleness = s - end
+			}
+			res.outcome = verifyOK
+			return res
+		}
+		if visible {
+			res.outcome = verifyMismatched
+		}
+		if now()+verifyPollInterval > deadline {
+			return res
+		}
+		select {
+		case <-time.After(verifyPollInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// verifyJob is a write queued for verification, end the time it completed.
+type verifyJob struct {
+	client *loomclient.ContractClient
+	op     Verifiable
+	end    time.Duration
+}
+
+// startVerifiers starts the goroutines verifying the writes queued with
+// queueVerify, off the workers so that their rate doesn't drop while the
+// nodes lag, recording the outcomes in b.report.verify until ctx is done.
+// The returned function waits for the queued writes to be verified.
+func (b *Work) startVerifiers(ctx context.Context) (wait func()) {
+	if !b.Verify {
+		return func() {}
+	}
+	b.verifyCh = make(chan verifyJob, verifyQueuePerWorker*b.C)
+	timeout := b.verifyTimeout()
+	var wg sync.WaitGroup
+	for i := 0; i < verifiersPerWorker*b.C; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range b.verifyCh {
+				if res := verifyWrite(ctx, job.client, job.op, job.end, timeout); res != nil {
+					b.report.verify.add(res)
+				}
+			}
+		}()
+	}
+	return func() {
+		close(b.verifyCh)
+		wg.Wait()
+	}
+}
+
+// queueVerify queues the write of op, completed at end, for verification.
+// It only waits if the queue is full, until ctx is done.
+func (b *Work) queueVerify(ctx context.Context, ws *WorkerState, op Verifiable, end time.Duration) {
+	select {
+	case b.verifyCh <- verifyJob{client: ws.verifyClient, op: op, end: end}:
+	case <-ctx.Done():
+	}
+}
+
+// verifyStats aggregates the verification of writes. It is safe for
+// concurrent use.
+type verifyStats struct {
+	mu         sync.Mutex
+	writes     int64
+	lost       int64
+	mismatched int64
+	reads      int64
+	staleness  *hdrHistogram
+}
+
+func (v *verifyStats) add(res *verifyResult) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.writes++
+	v.reads += int64(res.reads)
+	switch res.outcome {
+	case verifyOK:
+		v.staleness.record(res.staleness)
+	case verifyLost:
+		v.lost++
+	case verifyMismatched:
+		v.mismatched++
+	}
+}
+
+func (v *verifyStats) report() *VerifyReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return &VerifyReport{
+		Writes:              v.writes,
+		Visible:             v.staleness.count(),
+		Lost:                v.lost,
+		Mismatched:          v.mismatched,
+		Reads:               v.reads,
+		Average:             v.staleness.mean(),
+		Slowest:             v.staleness.max.Seconds(),
+		LatencyDistribution: v.staleness.distribution(pctls),
+	}
+}
+
+// VerifyReport summarizes the read-your-writes verification of the
+// successful writes of a run.
+type VerifyReport struct {
+	// Writes is the number of writes verified, Visible the number read
+	// back with the written value, Lost the number never found before the
+	// verify timeout and Mismatched the number found with another value.
+	Writes     int64 `json:"writes"`
+	Visible    int64 `json:"visible"`
+	Lost       int64 `json:"lost"`
+	Mismatched int64 `json:"mismatched"`
+
+	// Reads is the number of reads made to verify the writes.
+	Reads int64 `json:"reads"`
+
+	// Average, Slowest and LatencyDistribution describe the staleness of
+	// the visible writes, the time from the end of a write to the first
+	// read that saw it, in seconds. It is 0 for writes visible to the
+	// first read.
+	Average             float64               `json:"average"`
+	Slowest             float64               `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latency_distribution"`
+}
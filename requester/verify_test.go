@@ -0,0 +1,155 @@
+package requester
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// fakeWrite is a Verifiable write whose reads return the results of reads
+// in turn, and the last one once they are all used.
+type fakeWrite struct {
+	key   string
+	reads []fakeRead
+
+	mu sync.Mutex
+	n  int
+}
+
+type fakeRead struct {
+	visible, match bool
+	err            error
+}
+
+var errNotFound = errors.New("not found")
+
+var (
+	readMissing  = fakeRead{err: errNotFound}
+	readMatch    = fakeRead{visible: true, match: true}
+	readMismatch = fakeRead{visible: true}
+)
+
+func (w *fakeWrite) Key() string { return w.key }
+
+func (w *fakeWrite) ReadBack(ctx context.Context, c *loomclient.ContractClient) (visible, match bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r := w.reads[len(w.reads)-1]
+	if w.n < len(w.reads) {
+		r = w.reads[w.n]
+	}
+	w.n++
+	return r.visible, r.match, r.err
+}
+
+func (w *fakeWrite) readCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
+
+func TestVerifyWrite(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	tests := []struct {
+		name     string
+		reads    []fakeRead
+		outcome  int
+		numReads int
+		stale    bool
+	}{
+		{"visible", []fakeRead{readMatch}, verifyOK, 1, false},
+		{"stale then visible", []fakeRead{readMissing, readMissing, readMatch}, verifyOK, 3, true},
+		{"lost", []fakeRead{readMissing}, verifyLost, 0, false},
+		{"mismatched", []fakeRead{readMismatch}, verifyMismatched, 0, false},
+		{"mismatched then visible", []fakeRead{readMismatch, readMatch}, verifyOK, 2, true},
+		{"missing then mismatched", []fakeRead{readMissing, readMismatch}, verifyMismatched, 0, false},
+	}
+	for _, tt := range tests {
+		op := &fakeWrite{reads: tt.reads}
+		end := now()
+		res := verifyWrite(context.Background(), nil, op, end, timeout)
+		if res == nil {
+			t.Errorf("%s: no result", tt.name)
+			continue
+		}
+		if res.outcome != tt.outcome {
+			t.Errorf("%s: outcome = %d, want %d", tt.name, res.outcome, tt.outcome)
+		}
+		if res.reads != op.readCount() {
+			t.Errorf("%s: %d reads counted, %d made", tt.name, res.reads, op.readCount())
+		}
+		if tt.numReads > 0 && res.reads != tt.numReads {
+			t.Errorf("%s: %d reads, want %d", tt.name, res.reads, tt.numReads)
+		}
+		if tt.outcome != verifyOK && res.reads < int(timeout/verifyPollInterval)/2 {
+			t.Errorf("%s: gave up after %d reads, want about %d", tt.name, res.reads, timeout/verifyPollInterval)
+		}
+		// The staleness is the time to the first read that saw the write.
+		wantStale := time.Duration(tt.numReads-1) * verifyPollInterval
+		switch {
+		case !tt.stale && res.staleness != 0:
+			t.Errorf("%s: staleness = %v, want 0", tt.name, res.staleness)
+		case tt.stale && (res.staleness < wantStale || res.staleness > timeout):
+			t.Errorf("%s: staleness = %v, want at least %v", tt.name, res.staleness, wantStale)
+		}
+	}
+}
+
+func TestVerifyWriteCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	op := &fakeWrite{reads: []fakeRead{readMissing}}
+	if res := verifyWrite(ctx, nil, op, now(), time.Hour); res != nil {
+		t.Errorf("cancelled verification = %+v, want nil", res)
+	}
+}
+
+func TestVerifyStats(t *testing.T) {
+	v := &verifyStats{staleness: newHDRHistogram()}
+	v.add(&verifyResult{outcome: verifyOK, reads: 1})
+	v.add(&verifyResult{outcome: verifyOK, reads: 3, staleness: 40 * time.Millisecond})
+	v.add(&verifyResult{outcome: verifyLost, reads: 10})
+	v.add(&verifyResult{outcome: verifyMismatched, reads: 10})
+	r := v.report()
+	if r.Writes != 4 || r.Visible != 2 || r.Lost != 1 || r.Mismatched != 1 || r.Reads != 24 {
+		t.Errorf("report = %+v, want 4 writes, 2 visible, 1 lost, 1 mismatched, 24 reads", r)
+	}
+	if r.Slowest < 0.039 || r.Slowest > 0.041 || r.Average < 0.019 || r.Average > 0.021 {
+		t.Errorf("staleness slowest %v, average %v, want 0.04, 0.02", r.Slowest, r.Average)
+	}
+}
+
+// TestVerifiers checks that the writes are verified in the background: a
+// worker queues writes that stay invisible without waiting for them.
+func TestVerifiers(t *testing.T) {
+	b := &Work{Verify: true, C: 1, VerifyTimeout: 100 * time.Millisecond}
+	b.report = &report{verify: &verifyStats{staleness: newHDRHistogram()}}
+	ctx := context.Background()
+	wait := b.startVerifiers(ctx)
+
+	ws := &WorkerState{}
+	writes := 2 * verifiersPerWorker
+	start := time.Now()
+	for i := 0; i < writes; i++ {
+		op := &fakeWrite{reads: []fakeRead{readMissing}}
+		if i%2 == 0 {
+			op.reads = []fakeRead{readMatch}
+		}
+		b.queueVerify(ctx, ws, op, now())
+	}
+	if queued := time.Since(start); queued > 50*time.Millisecond {
+		t.Errorf("queueing %d writes took %v", writes, queued)
+	}
+	wait()
+	r := b.report.verify.report()
+	if r.Writes != int64(writes) || r.Visible != int64(writes/2) || r.Lost != int64(writes/2) {
+		t.Errorf("report = %+v, want %d writes, half of them lost", r, writes)
+	}
+
+	// Nothing is verified without Verify.
+	(&Work{}).startVerifiers(ctx)()
+}
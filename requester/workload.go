@@ -0,0 +1,144 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// WorkerState is the state of the worker a workload generates operations
+// for.
+type WorkerState struct {
+	// ID is the index of the worker in the run, from 0.
+	ID int
+
+	// RunID identifies the run, to tell apart the keys written by
+	// different runs.
+	RunID string
+
+	// Seq is the number of operations the worker made so far.
+	Seq uint64
+
+	// Rand is the random source of the worker.
+	Rand *rand.Rand
+
+	// Node is the name of the node the next operation is sent to, Client
+	// calls the contract on it with the worker's account, and RPC is the
+	// client it sends the calls with. They change between operations when
+	// requests are distributed across several nodes.
+	Node   string
+	Client *loomclient.ContractClient
+	RPC    *loomclient.DAppChainRPCClient
+
+	// verifyClient reads back writes in verify mode.
+	verifyClient *loomclient.ContractClient
+
+	// nodes are the clients of the worker for every node, and node the
+	// one the next operation is sent to.
+	nodes []*workerNode
+	node  *workerNode
+}
+
+// Op is one operation of a workload.
+type Op interface {
+	// Name is the name the operation is reported under.
+	Name() string
+
+	// Execute performs the operation. The error of a failed operation is
+	// counted in the report, it doesn't stop the run.
+	Execute(ctx context.Context, w *WorkerState) error
+}
+
+// BatchOp is implemented by ops that can be sent in a JSON-RPC batch with
+// other ops, see Work.BatchSize. The ops of the built-in workloads implement
+// it.
+type BatchOp interface {
+	Op
+
+	// Call returns the contract call the op makes, a tx if tx is set or a
+	// query otherwise. Once the batch is done, the error of the call is the
+	// error of the op.
+	Call() (call *loomclient.ContractCall, tx bool)
+}
+
+// Classifier is implemented by ops that categorize their own errors, such
+// as a read that found a stale value. The errors of other ops, or those
+// Classify returns "" for, are categorized by loomclient.ErrorCategory.
+type Classifier interface {
+	Classify(err error) string
+}
+
+// Workload generates the operations of a worker. Every worker of a run gets
+// its own Workload, so implementations need not be safe for concurrent use.
+type Workload interface {
+	// Setup is called once before the worker makes its first operation,
+	// for example to write the keys it reads. It isn't timed. An error
+	// stops the run.
+	Setup(ctx context.Context, w *WorkerState) error
+
+	// Next returns the next operation of the worker.
+	Next(w *WorkerState) Op
+}
+
+// WorkloadConfig is the configuration of a run that workloads are created
+// with.
+type WorkloadConfig struct {
+	// ContractMethod and RequestBody are the method and argument of the
+	// contract call given by -m.
+	ContractMethod string
+	RequestBody    proto.Message
+
+	// Ratio is the fraction of operations that are reads, for workloads
+	// mixing reads and writes.
+	Ratio float64
+}
+
+// WorkloadFactory returns the workload of a worker.
+type WorkloadFactory func(cfg WorkloadConfig) (Workload, error)
+
+var (
+	workloadsMu sync.RWMutex
+	workloads   = make(map[string]WorkloadFactory)
+)
+
+// RegisterWorkload makes a workload available under name, to select with
+// Work.TransactionType or the -x flag. It is meant to be called from an init
+// function, and panics if name is already registered or factory is nil.
+func RegisterWorkload(name string, factory WorkloadFactory) {
+	workloadsMu.Lock()
+	defer workloadsMu.Unlock()
+	if factory == nil {
+		panic("requester: RegisterWorkload factory is nil")
+	}
+	if _, dup := workloads[name]; dup {
+		panic("requester: RegisterWorkload called twice for workload " + name)
+	}
+	workloads[name] = factory
+}
+
+// Workloads returns the sorted names of the registered workloads.
+func Workloads() []string {
+	workloadsMu.RLock()
+	defer workloadsMu.RUnlock()
+	var names []string
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newWorkload(name string, cfg WorkloadConfig) (Workload, error) {
+	workloadsMu.RLock()
+	factory, ok := workloads[name]
+	workloadsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("requester: unknown workload %q", name)
+	}
+	return factory(cfg)
+}
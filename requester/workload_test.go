@@ -0,0 +1,196 @@
+package requester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jsimnz/loombench/loomclient"
+)
+
+// panics reports whether f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	f()
+	return false
+}
+
+func TestRegisterWorkload(t *testing.T) {
+	factory := func(cfg WorkloadConfig) (Workload, error) { return &callWorkload{}, nil }
+	if panics(func() { RegisterWorkload("test-register", factory) }) {
+		t.Fatal("registering a new workload panicked")
+	}
+	if !panics(func() { RegisterWorkload("test-register", factory) }) {
+		t.Error("registering a workload twice didn't panic")
+	}
+	if !panics(func() { RegisterWorkload(WorkloadCall, factory) }) {
+		t.Error("registering a built-in workload again didn't panic")
+	}
+	if !panics(func() { RegisterWorkload("test-nil", nil) }) {
+		t.Error("registering a nil factory didn't panic")
+	}
+
+	names := Workloads()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("Workloads() = %v, not sorted", names)
+	}
+	for _, want := range []string{WorkloadCall, WorkloadMixed, WorkloadRead, WorkloadWrite, "test-register"} {
+		found := false
+		for _, name := range names {
+			found = found || name == want
+		}
+		if !found {
+			t.Errorf("Workloads() = %v, missing %s", names, want)
+		}
+	}
+	for _, name := range names {
+		if name == "test-nil" {
+			t.Errorf("Workloads() = %v, has the workload with a nil factory", names)
+		}
+	}
+
+	if _, err := newWorkload("test-register", WorkloadConfig{}); err != nil {
+		t.Errorf("newWorkload() of a registered workload: %v", err)
+	}
+	if _, err := newWorkload("no-such-workload", WorkloadConfig{}); err == nil || !strings.Contains(err.Error(), "unknown workload") {
+		t.Errorf("newWorkload() of an unknown workload error = %v, want unknown workload", err)
+	}
+}
+
+func TestStoreWorkloadKeyRing(t *testing.T) {
+	l := &storeWorkload{ratio: 0.5}
+	w := &WorkerState{ID: 3, RunID: "run", Rand: rand.New(rand.NewSource(1))}
+	const extra = 10
+	for i := 0; i < maxReadKeys+extra; i++ {
+		if op := l.set(w); string(op.key) != fmt.Sprintf("loombench-run-3-%d", i) {
+			t.Fatalf("key %d = %s", i, op.key)
+		}
+	}
+	if len(l.keys) != maxReadKeys {
+		t.Fatalf("%d keys kept, want %d", len(l.keys), maxReadKeys)
+	}
+	if l.next != extra {
+		t.Errorf("next = %d, want %d", l.next, extra)
+	}
+	// The oldest keys were overwritten by the newest.
+	kept := make(map[string]bool)
+	for _, key := range l.keys {
+		kept[string(key)] = true
+	}
+	for i := 0; i < maxReadKeys+extra; i++ {
+		key := fmt.Sprintf("loombench-run-3-%d", i)
+		if want := i >= extra; kept[key] != want {
+			t.Errorf("key %d kept = %v, want %v", i, kept[key], want)
+		}
+	}
+
+	writes := &storeWorkload{ratio: 0}
+	writes.set(w)
+	if len(writes.keys) != 0 {
+		t.Errorf("write-only workload kept %d keys to read", len(writes.keys))
+	}
+}
+
+func TestStoreWorkloadRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		ratio float64
+		reads float64
+	}{
+		{WorkloadWrite, 0, 0},
+		{WorkloadRead, 0, 1},
+		{WorkloadMixed, 0, 0},
+		{WorkloadMixed, 1, 1},
+		{WorkloadMixed, 0.3, 0.3},
+	}
+	const ops = 10000
+	for _, tt := range tests {
+		wl, err := newWorkload(tt.name, WorkloadConfig{Ratio: tt.ratio})
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.name, tt.ratio, err)
+			continue
+		}
+		l := wl.(*storeWorkload)
+		w := &WorkerState{RunID: "run", Rand: rand.New(rand.NewSource(1))}
+		// As Setup does, without a node to write to.
+		if l.ratio > 0 {
+			l.set(w)
+		}
+		var reads int
+		for i := 0; i < ops; i++ {
+			switch op := l.Next(w).(type) {
+			case *getOp:
+				reads++
+			case *setOp:
+			default:
+				t.Fatalf("%s %v: op %T", tt.name, tt.ratio, op)
+			}
+		}
+		if got := float64(reads) / ops; math.Abs(got-tt.reads) > 0.02 {
+			t.Errorf("%s %v: %.3f of the ops are reads, want %.3f", tt.name, tt.ratio, got, tt.reads)
+		}
+	}
+
+	for _, ratio := range []float64{-0.1, 1.5} {
+		if _, err := newWorkload(WorkloadMixed, WorkloadConfig{Ratio: ratio}); err == nil {
+			t.Errorf("mixed workload with ratio %v: no error", ratio)
+		}
+	}
+}
+
+var errStale = errors.New("stale value")
+
+// classifiedOp categorizes errStale as stale, and leaves the other errors to
+// loomclient.ErrorCategory.
+type classifiedOp struct{ getOp }
+
+func (o *classifiedOp) Classify(err error) string {
+	if err == errStale {
+		return "stale"
+	}
+	return ""
+}
+
+func TestClassify(t *testing.T) {
+	timeout := context.DeadlineExceeded
+	other := errors.New("boom")
+	tests := []struct {
+		op   Op
+		err  error
+		want string
+	}{
+		{&getOp{}, nil, ""},
+		{&getOp{}, errStale, loomclient.ErrCategoryOther},
+		{&getOp{}, other, loomclient.ErrCategoryOther},
+		{&classifiedOp{}, nil, ""},
+		{&classifiedOp{}, errStale, "stale"},
+		{&classifiedOp{}, other, loomclient.ErrCategoryOther},
+		{&classifiedOp{}, timeout, loomclient.ErrorCategory(timeout)},
+		{nil, other, loomclient.ErrCategoryOther},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.op, tt.err); got != tt.want {
+			t.Errorf("classify(%T, %v) = %q, want %q", tt.op, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCallWorkload(t *testing.T) {
+	wl, err := newWorkload(WorkloadCall, WorkloadConfig{ContractMethod: "Ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &WorkerState{Rand: rand.New(rand.NewSource(1))}
+	first := wl.Next(w)
+	if first.Name() != "Ping" || !reflect.DeepEqual(wl.Next(w), first) {
+		t.Errorf("call workload ops = %s, want the same Ping op every time", first.Name())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// loadScenario sets flags from the JSON scenario file at path. The file is a
+// single object mapping flag names (without the leading dash) to values, for
+// example:
+//
+//	{"x": "write", "c": 20, "z": "5m", "warmup": "30s"}
+//
+// Array values set the flag once per element, for flags that can be repeated.
+// Flags given explicitly on the command line take precedence over the file.
+func loadScenario(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var values map[string]interface{}
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return fmt.Errorf("invalid scenario file %s: %v", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, v := range values {
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("invalid scenario file %s: unknown option %q", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+		vals, ok := v.([]interface{})
+		if !ok {
+			vals = []interface{}{v}
+		}
+		for _, val := range vals {
+			if err := flag.Set(name, fmt.Sprint(val)); err != nil {
+				return fmt.Errorf("invalid scenario file %s: option %q: %v", path, name, err)
+			}
+		}
+	}
+	return nil
+}
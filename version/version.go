@@ -1,3 +1,12 @@
 package version
 
-var ContractVersion = "0.0.2"
\ No newline at end of file
+var ContractVersion = "0.0.2"
+
+// Version is the version of loombench.
+var Version = "0.0.2"
+
+// GitCommit is the source revision loombench was built from. It is set at
+// build time with:
+//
+//	go build -ldflags "-X github.com/jsimnz/loombench/version.GitCommit=$(git rev-parse HEAD)"
+var GitCommit = ""